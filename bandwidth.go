@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// withBandwidth returns a middleware pacing response bodies to about
+// bytesPerSec, or leaving them untouched when bytesPerSec is 0.
+//
+// Bodies are written in chunks of a tenth of a second's worth of bytes, each
+// followed by a sleep keeping the running average at the configured rate, so
+// large responses take proportionally longer than small ones.
+func withBandwidth(bytesPerSec int) func(http.Handler) http.Handler {
+	if bytesPerSec <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&throttledWriter{ResponseWriter: w, r: r, rate: bytesPerSec}, r)
+		})
+	}
+}
+
+// throttledWriter paces writes to rate bytes per second.
+type throttledWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	rate    int
+	start   time.Time // first write
+	written int64     // bytes written since start
+}
+
+// Write implements http.ResponseWriter. It stops early, returning the
+// context error, when the client goes away.
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	if tw.start.IsZero() {
+		tw.start = time.Now()
+	}
+	chunk := max(tw.rate/10, 1)
+	total := 0
+	for len(p) > 0 {
+		n := min(chunk, len(p))
+		written, err := tw.ResponseWriter.Write(p[:n])
+		total += written
+		if err != nil {
+			return total, err
+		}
+		p = p[n:]
+		tw.written += int64(written)
+		if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		due := tw.start.Add(time.Duration(tw.written * int64(time.Second) / int64(tw.rate)))
+		select {
+		case <-time.After(time.Until(due)):
+		case <-tw.r.Context().Done():
+			return total, tw.r.Context().Err()
+		}
+	}
+	return total, nil
+}
+
+// Flush implements http.Flusher.
+func (tw *throttledWriter) Flush() {
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (tw *throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBandwidth(t *testing.T) {
+	large := strings.Repeat("x", 6000)
+	input := parseInput(t, `{"routes": [
+		{"method": "GET", "path": "/small", "response": {"status": 200, "body": "tiny"}},
+		{"method": "GET", "path": "/large", "response": {"status": 200, "body": "`+large+`"}},
+		{"method": "GET", "path": "/fast", "bandwidth": 1000000, "response": {"status": 200, "body": "`+large+`"}}
+	]}`)
+	input.Bandwidth = 20000
+	srv := serveInput(t, input)
+
+	elapsed := func(path, want string) time.Duration {
+		start := time.Now()
+		if _, body := get(t, srv.URL+path); body != `"`+want+`"` {
+			t.Errorf("%s: body of %d bytes, want %d", path, len(body), len(want)+2)
+		}
+		return time.Since(start)
+	}
+
+	small, slow, fast := elapsed("/small", "tiny"), elapsed("/large", large), elapsed("/fast", large)
+	// 6 KB at 20 KB/s takes about 300ms; the small body and the route
+	// overriding the rate finish well before that.
+	if slow < 250*time.Millisecond {
+		t.Errorf("large body took %s, want at least 250ms", slow)
+	}
+	if small >= slow/2 || fast >= slow/2 {
+		t.Errorf("small body took %s and per-route bandwidth %s, large body %s", small, fast, slow)
+	}
+}
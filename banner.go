@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// banner is the ASCII art printed on startup in interactive terminals.
+const banner = "\n" +
+	"                       _\n" +
+	"  _ __ ___   ___   ___| | _____ _ __\n" +
+	" | '_ ` _ \\ / _ \\ / __| |/ / _ \\ '__|\n" +
+	" | | | | | | (_) | (__|   <  __/ |\n" +
+	" |_| |_| |_|\\___/ \\___|_|\\_\\___|_|\n"
+
+// printBanner writes the startup banner with the version and route count,
+// unless it is disabled with -no-banner or w is not an interactive terminal.
+func printBanner(w io.Writer, routes int, noBanner, interactive bool) {
+	if noBanner || !interactive {
+		return
+	}
+	fmt.Fprint(w, banner)
+	fmt.Fprintf(w, "  Mocker %s · routes: %d\n\n", appVersion, routes)
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a
+// pipe or file, e.g. when running in CI.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintBanner(t *testing.T) {
+	for _, tt := range []struct {
+		name                  string
+		noBanner, interactive bool
+		want                  bool
+	}{
+		{"terminal", false, true, true},
+		{"-no-banner", true, true, false},
+		{"not a terminal", false, false, false},
+	} {
+		var out bytes.Buffer
+		printBanner(&out, 3, tt.noBanner, tt.interactive)
+		if got := strings.Contains(out.String(), "Mocker "+appVersion+" · routes: 3"); got != tt.want {
+			t.Errorf("%s: banner printed = %v, want %v:\n%s", tt.name, got, tt.want, out.String())
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if isTerminal(f) {
+		t.Error("regular file reported as a terminal")
+	}
+}
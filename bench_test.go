@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// discardWriter is a reusable ResponseWriter that drops everything written.
+type discardWriter struct{ header http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func TestBenchHandler(t *testing.T) {
+	handler := newBenchHandler("/bench", `{"ok":true}`)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	res, body := get(t, srv.URL+"/bench")
+	if res.StatusCode != 200 || body != `{"ok":true}` {
+		t.Errorf("got %d %s, want 200 {\"ok\":true}", res.StatusCode, body)
+	}
+	if got := res.Header.Get("Content-Length"); got != "11" {
+		t.Errorf("Content-Length = %q, want 11", got)
+	}
+	if res, _ := get(t, srv.URL+"/other"); res.StatusCode != 404 {
+		t.Errorf("other path status = %d, want 404", res.StatusCode)
+	}
+	if got := handler.served.Load(); got != 1 {
+		t.Errorf("served = %d, want 1", got)
+	}
+
+	w := &discardWriter{header: http.Header{}}
+	req := httptest.NewRequest(http.MethodGet, "/bench", nil)
+	if allocs := testing.AllocsPerRun(100, func() { handler.ServeHTTP(w, req) }); allocs != 0 {
+		t.Errorf("ServeHTTP allocates %.1f times per request, want 0", allocs)
+	}
+}
+
+func BenchmarkBenchHandler(b *testing.B) {
+	srv := httptest.NewServer(newBenchHandler("/bench", `{"ok":true}`))
+	defer srv.Close()
+	client := srv.Client()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		res, err := client.Get(srv.URL + "/bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		res.Body.Close()
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// fileSequence plays back the bodyFiles of a response, one file per call.
+type fileSequence struct {
+	bodies       [][]byte
+	contentTypes []string // per file, from the extension unless contentType is set
+	loop         bool     // start over after the last file instead of repeating it
+	calls        atomic.Int64
+}
+
+// loadFileSequence reads every file up front so a missing one fails at
+// startup. mode is "loop" (default) or "stop".
+func loadFileSequence(paths []string, mode, contentType string) (*fileSequence, error) {
+	if mode != "" && mode != "loop" && mode != "stop" {
+		return nil, fmt.Errorf("bodyFilesMode must be loop or stop, got %q", mode)
+	}
+	seq := &fileSequence{loop: mode != "stop"}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("bodyFiles: %w", err)
+		}
+		ct := contentType
+		if ct == "" {
+			ct = mime.TypeByExtension(filepath.Ext(path))
+		}
+		seq.bodies = append(seq.bodies, data)
+		seq.contentTypes = append(seq.contentTypes, ct)
+	}
+	return seq, nil
+}
+
+// next returns the body and content type for the next call.
+func (s *fileSequence) next() ([]byte, string) {
+	i := int(s.calls.Add(1) - 1)
+	if s.loop {
+		i %= len(s.bodies)
+	} else {
+		i = min(i, len(s.bodies)-1)
+	}
+	return s.bodies[i], s.contentTypes[i]
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestBodyFiles(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 1; i <= 3; i++ {
+		file := writeConfig(t, dir, fmt.Sprintf("page%d.json", i), fmt.Sprintf(`{"page":%d}`, i))
+		files = append(files, filepath.ToSlash(file))
+	}
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/loop", "response": {"status": 200, "bodyFiles": ["`+files[0]+`", "`+files[1]+`"]}},
+		{"method": "GET", "path": "/stop", "response": {"status": 200, "bodyFiles": ["`+files[0]+`", "`+files[1]+`", "`+files[2]+`"], "bodyFilesMode": "stop"}}
+	]}`)
+
+	for _, tt := range []struct {
+		path string
+		want []string
+	}{
+		{"/loop", []string{`{"page":1}`, `{"page":2}`, `{"page":1}`}},
+		{"/stop", []string{`{"page":1}`, `{"page":2}`, `{"page":3}`, `{"page":3}`}},
+	} {
+		for i, want := range tt.want {
+			res, body := get(t, srv.URL+tt.path)
+			if body != want {
+				t.Errorf("%s call %d: body = %s, want %s", tt.path, i+1, body, want)
+			}
+			if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("%s call %d: Content-Type = %q", tt.path, i+1, ct)
+			}
+		}
+	}
+}
+
+func TestLoadFileSequenceErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := writeConfig(t, dir, "a.json", "{}")
+	if _, err := loadFileSequence([]string{file}, "shuffle", ""); err == nil {
+		t.Error("unknown bodyFilesMode accepted")
+	}
+	if _, err := loadFileSequence([]string{file, filepath.Join(dir, "missing.json")}, "", ""); err == nil {
+		t.Error("missing file accepted")
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveConfigPath picks the config file to load, in order:
+//
+//  1. the -path flag
+//  2. the MOCKER_CONFIG environment variable
+//  3. ./mocker.json
+//  4. $XDG_CONFIG_HOME/mocker/config.json (default ~/.config/mocker/config.json)
+//
+// An explicit -path or MOCKER_CONFIG is returned as is, so a typo there is
+// reported instead of silently falling back. Otherwise the first existing
+// file wins and the error lists every location that was tried.
+func resolveConfigPath(flagPath string) (string, error) {
+	if flagPath != "" {
+		return flagPath, nil
+	}
+	if env := os.Getenv("MOCKER_CONFIG"); env != "" {
+		return env, nil
+	}
+
+	candidates := []string{"./mocker.json"}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		candidates = append(candidates, filepath.Join(configHome, "mocker", "config.json"))
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no config file found; tried -path, $MOCKER_CONFIG, %s (create one with: mocker --download=mocker.json)",
+		strings.Join(candidates, ", "))
+}
+
+// loadConfig reads the config at path and, when overridePath is set,
+// deep-merges the override config over it. Both may extend a base config
+// (see resolveExtends). It also returns every file read, for -watch.
+//
+// Merging happens on the raw JSON objects so every field, present or future,
+// is handled the same way: objects are merged key by key, other values in the
+// override win, and routes are merged by method+path (see mergeRoutes).
+func loadConfig(path, overridePath string) (inputType, []string, error) {
+	var input inputType
+
+	base, files, err := resolveExtends(path, nil)
+	if err != nil {
+		return input, nil, err
+	}
+	if overridePath != "" {
+		override, overrideFiles, err := resolveExtends(overridePath, nil)
+		if err != nil {
+			return input, nil, err
+		}
+		base = mergeConfig(base, override)
+		files = append(files, overrideFiles...)
+	}
+
+	data, err := json.Marshal(base)
+	if err != nil {
+		return input, nil, err
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return input, nil, fmt.Errorf("error in Unmarshal of the JSON: %w", err)
+	}
+	return input, files, nil
+}
+
+// resolveExtends reads the config at path and, when it declares
+// "extends": "base.json", deep-merges it over that base config the same way
+// -override is merged, the extending config winning. The base path is
+// relative to the extending file, and may itself extend another config.
+//
+// chain lists the files already being resolved, to report circular extends.
+// The returned files are path followed by the configs it extends.
+func resolveExtends(path string, chain []string) (map[string]any, []string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, seen := range chain {
+		if seen == abs {
+			cycle := append(append([]string{}, chain[i:]...), abs)
+			return nil, nil, fmt.Errorf("circular extends: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	m, err := readConfigMap(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	extends, ok := m["extends"]
+	if !ok {
+		return m, []string{path}, nil
+	}
+	delete(m, "extends")
+	basePath, ok := extends.(string)
+	if !ok || basePath == "" {
+		return nil, nil, fmt.Errorf("invalid extends in %s: must be the path of a config file", path)
+	}
+	if !filepath.IsAbs(basePath) {
+		basePath = filepath.Join(filepath.Dir(path), basePath)
+	}
+	base, files, err := resolveExtends(basePath, append(chain, abs))
+	if err != nil {
+		return nil, nil, err
+	}
+	return mergeConfig(base, m), append([]string{path}, files...), nil
+}
+
+// readConfigMap reads a JSON config file into a generic object, keeping
+// numbers as json.Number so large integers survive a merge unchanged.
+func readConfigMap(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error in reading the file: %w", err)
+	}
+
+	var m map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("error in Unmarshal of the JSON (%s): %w", path, err)
+	}
+	return m, nil
+}
+
+// mergeConfig deep-merges override into base and returns base.
+func mergeConfig(base, override map[string]any) map[string]any {
+	for key, value := range override {
+		if key == "routes" {
+			baseRoutes, _ := base[key].([]any)
+			overrideRoutes, _ := value.([]any)
+			base[key] = mergeRoutes(baseRoutes, overrideRoutes, rawRouteKey)
+			continue
+		}
+
+		baseObj, baseIsObj := base[key].(map[string]any)
+		overrideObj, overrideIsObj := value.(map[string]any)
+		if baseIsObj && overrideIsObj {
+			base[key] = mergeConfig(baseObj, overrideObj)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
+
+// mergeRoutes replaces base routes that share method+path with an override
+// route, keeping their position, and appends the remaining override routes.
+//
+// All override routes for a method+path replace all base routes for it, so
+// match variants are swapped as a set.
+func mergeRoutes[T any](base, override []T, routeKey func(T) string) []T {
+	byKey := map[string][]T{}
+	var order []string
+	for _, r := range override {
+		key := routeKey(r)
+		if _, seen := byKey[key]; !seen {
+			order = append(order, key)
+		}
+		byKey[key] = append(byKey[key], r)
+	}
+
+	merged := make([]T, 0, len(base)+len(override))
+	used := map[string]bool{}
+	for _, r := range base {
+		key := routeKey(r)
+		replacement, ok := byKey[key]
+		if !ok {
+			merged = append(merged, r)
+			continue
+		}
+		if !used[key] {
+			merged = append(merged, replacement...)
+			used[key] = true
+		}
+	}
+	for _, key := range order {
+		if !used[key] {
+			merged = append(merged, byKey[key]...)
+		}
+	}
+	return merged
+}
+
+// rawRouteKey identifies a raw route object by its upper-cased method and path.
+func rawRouteKey(r any) string {
+	obj, _ := r.(map[string]any)
+	method, _ := obj["method"].(string)
+	path, _ := obj["path"].(string)
+	return normalizeMethod(method) + " " + path
+}
+
+// routeKey identifies a route by its upper-cased method and path.
+func routeKey(r routesType) string {
+	return normalizeMethod(r.Method) + " " + r.Path
+}
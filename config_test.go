@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file named name in dir and returns its path.
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigOverride(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "base.json", `{"port": "8080", "routes": [
+		{"method": "GET", "path": "/users", "response": {"status": 200, "body": "base users"}},
+		{"method": "GET", "path": "/health", "response": {"status": 200, "body": "ok"}}
+	]}`)
+	override := writeConfig(t, dir, "local.json", `{"port": "9090", "routes": [
+		{"method": "get", "path": "/users", "response": {"status": 500, "body": "override users"}},
+		{"method": "POST", "path": "/users", "response": {"status": 201}}
+	]}`)
+
+	input, _, err := loadConfig(base, override)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if input.Port != "9090" {
+		t.Errorf("port = %q, want 9090", input.Port)
+	}
+	want := []struct {
+		method, path string
+		status       int
+	}{
+		{"get", "/users", 500},
+		{"GET", "/health", 200},
+		{"POST", "/users", 201},
+	}
+	if len(input.Routes) != len(want) {
+		t.Fatalf("got %d routes, want %d: %+v", len(input.Routes), len(want), input.Routes)
+	}
+	for i, w := range want {
+		r := input.Routes[i]
+		if r.Method != w.method || r.Path != w.path || r.Response.Status != w.status {
+			t.Errorf("route %d = %s %s %d, want %s %s %d", i, r.Method, r.Path, r.Response.Status, w.method, w.path, w.status)
+		}
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	t.Setenv("MOCKER_CONFIG", "")
+
+	if _, err := resolveConfigPath(""); err == nil || !strings.Contains(err.Error(), "mocker.json") {
+		t.Errorf("without any config: err = %v, want one listing the locations tried", err)
+	}
+	writeConfig(t, dir, "example.json", `{}`)
+	if got, err := resolveConfigPath(""); err == nil {
+		t.Errorf("with only ./example.json: got %q, want it ignored", got)
+	}
+
+	xdg := filepath.Join(dir, "xdg", "mocker", "config.json")
+	if err := os.MkdirAll(filepath.Dir(xdg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, filepath.Dir(xdg), "config.json", `{}`)
+	if got, _ := resolveConfigPath(""); got != xdg {
+		t.Errorf("with only the XDG config: got %q, want %q", got, xdg)
+	}
+
+	writeConfig(t, dir, "mocker.json", `{}`)
+	if got, _ := resolveConfigPath(""); got != "./mocker.json" {
+		t.Errorf("with ./mocker.json: got %q, want ./mocker.json", got)
+	}
+
+	t.Setenv("MOCKER_CONFIG", "/etc/from-env.json")
+	if got, _ := resolveConfigPath(""); got != "/etc/from-env.json" {
+		t.Errorf("with MOCKER_CONFIG set: got %q, want /etc/from-env.json", got)
+	}
+	if got, _ := resolveConfigPath("flag.json"); got != "flag.json" {
+		t.Errorf("with -path set: got %q, want flag.json", got)
+	}
+}
+
+func TestLoadConfigExtends(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "base.json", `{"port": "8080", "basePath": "/api", "routes": [
+		{"method": "GET", "path": "/users", "response": {"status": 200, "body": "base users"}},
+		{"method": "GET", "path": "/health", "response": {"status": 200, "body": "ok"}}
+	]}`)
+	if err := os.Mkdir(filepath.Join(dir, "env"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	child := writeConfig(t, filepath.Join(dir, "env"), "child.json", `{"extends": "../base.json", "port": "9090", "routes": [
+		{"method": "GET", "path": "/users", "response": {"status": 503}}
+	]}`)
+
+	input, files, err := loadConfig(child, "")
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if input.Port != "9090" {
+		t.Errorf("port = %q, want the child's 9090", input.Port)
+	}
+	if input.BasePath != "/api" {
+		t.Errorf("basePath = %q, want the base's /api", input.BasePath)
+	}
+	if len(input.Routes) != 2 || input.Routes[0].Response.Status != 503 || input.Routes[1].Path != "/health" {
+		t.Errorf("routes = %+v, want the child's /users and the base's /health", input.Routes)
+	}
+	if want := []string{child, filepath.Join(dir, "base.json")}; !slices.Equal(files, want) {
+		t.Errorf("files = %q, want the chain %q for -watch", files, want)
+	}
+
+	writeConfig(t, dir, "a.json", `{"extends": "b.json"}`)
+	writeConfig(t, dir, "b.json", `{"extends": "a.json"}`)
+	if _, _, err := loadConfig(filepath.Join(dir, "a.json"), ""); err == nil || !strings.Contains(err.Error(), "circular extends") {
+		t.Errorf("a -> b -> a: err = %v, want a circular extends error", err)
+	}
+	writeConfig(t, dir, "bad.json", `{"extends": 1}`)
+	if _, _, err := loadConfig(filepath.Join(dir, "bad.json"), ""); err == nil || !strings.Contains(err.Error(), "invalid extends") {
+		t.Errorf("non-string extends: err = %v, want an invalid extends error", err)
+	}
+}
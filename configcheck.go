@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// configFinding is a single problem reported by -config-check.
+type configFinding struct {
+	Scenario string `json:"scenario,omitempty"` // Scenario whose merged routes have the problem
+	Route    int    `json:"route,omitempty"`    // 1-based route number, 0 for config-wide problems
+	Method   string `json:"method,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Error    string `json:"error"`
+}
+
+// configCheckResult is the -config-check -json output.
+//
+// Example:
+//
+//	{ "ok": false, "findings": [ { "route": 2, "method": "GET", "path": "/a", "error": "invalid bodyBase64: ..." } ] }
+type configCheckResult struct {
+	OK       bool            `json:"ok"`
+	Findings []configFinding `json:"findings"`
+}
+
+// checkConfig validates a loaded config the way BuildRouter would, but
+// collects every problem instead of stopping at the first one.
+func checkConfig(input inputType) []configFinding {
+	findings := checkRoutes(input, "")
+	reported := map[string]bool{}
+	for _, f := range findings {
+		reported[f.Error] = true
+	}
+	// Scenario routes are merged over the base routes; problems inherited
+	// from the base routes are only reported once.
+	for _, name := range scenarioNames(input) {
+		scoped := input
+		scoped.Routes = mergeRoutes(input.Routes, input.Scenarios[name].Routes, routeKey)
+		for _, f := range checkRoutes(scoped, name) {
+			if !reported[f.Error] {
+				findings = append(findings, f)
+			}
+		}
+	}
+	for _, prefix := range slices.Sorted(maps.Keys(input.Static)) {
+		if err := checkStaticDir(prefix, input.Static[prefix]); err != nil {
+			findings = append(findings, configFinding{Error: err.Error()})
+		}
+	}
+	if input.Scenario != "" {
+		if _, ok := input.Scenarios[input.Scenario]; !ok {
+			findings = append(findings, configFinding{Error: fmt.Sprintf("unknown scenario %q", input.Scenario)})
+		}
+	}
+	return findings
+}
+
+// checkRoutes validates one route set.
+func checkRoutes(input inputType, scenario string) []configFinding {
+	var findings []configFinding
+	if err := validateRoutes(input); err != nil {
+		findings = append(findings, configFinding{Scenario: scenario, Error: err.Error()})
+	}
+
+	state := newRouterState()
+	for i, route := range input.Routes {
+		route.Method = normalizeMethod(route.Method)
+		route.Path = input.fullPath(route.Path)
+		if _, err := newRouteHandler(route, state); err != nil {
+			findings = append(findings, configFinding{
+				Scenario: scenario,
+				Route:    i + 1,
+				Method:   route.Method,
+				Path:     route.Path,
+				Error:    err.Error(),
+			})
+		}
+	}
+	return findings
+}
+
+// runConfigCheck loads the config with load, reports the findings to w as
+// text or JSON and returns the process exit code: 0 when the config is
+// valid, 1 otherwise.
+func runConfigCheck(w io.Writer, load func() (inputType, error), asJSON bool) int {
+	var findings []configFinding
+	input, err := load()
+	if err != nil {
+		findings = []configFinding{{Error: err.Error()}}
+	} else {
+		findings = checkConfig(input)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		result := configCheckResult{OK: len(findings) == 0, Findings: findings}
+		if result.Findings == nil {
+			result.Findings = []configFinding{}
+		}
+		if err := enc.Encode(result); err != nil {
+			return 1
+		}
+	} else if len(findings) == 0 {
+		fmt.Fprintln(w, "✅ Config is valid")
+	} else {
+		for _, f := range findings {
+			prefix := ""
+			if f.Scenario != "" {
+				prefix = fmt.Sprintf("scenario %q: ", f.Scenario)
+			}
+			fmt.Fprintf(w, "❌ %s%s\n", prefix, f.Error)
+		}
+	}
+
+	if len(findings) > 0 {
+		return 1
+	}
+	return 0
+}
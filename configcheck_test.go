@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunConfigCheck(t *testing.T) {
+	load := func(config string) func() (inputType, error) {
+		return func() (inputType, error) { return parseInput(t, config), nil }
+	}
+
+	var out bytes.Buffer
+	valid := load(`{"routes": [{"method": "GET", "path": "/ok", "response": {"status": 200}}]}`)
+	if code := runConfigCheck(&out, valid, true); code != 0 {
+		t.Fatalf("valid config: exit code %d, output:\n%s", code, out.String())
+	}
+	var result configCheckResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || !result.OK || result.Findings == nil || len(result.Findings) != 0 {
+		t.Errorf("valid config: output %s (err %v), want ok with empty findings", out.String(), err)
+	}
+
+	out.Reset()
+	broken := load(`{"routes": [
+		{"method": "GET", "path": "/ok", "response": {"status": 200}},
+		{"method": "GET", "path": "/bin", "response": {"status": 200, "bodyBase64": "%%%"}},
+		{"method": "GET", "path": "/ok", "response": {"status": 200}}
+	]}`)
+	if code := runConfigCheck(&out, broken, true); code != 1 {
+		t.Fatalf("broken config: exit code %d, want 1", code)
+	}
+	result = configCheckResult{}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("broken config: invalid JSON %q: %v", out.String(), err)
+	}
+	if result.OK || len(result.Findings) != 2 {
+		t.Fatalf("broken config: result %+v, want two findings", result)
+	}
+	if f := result.Findings[1]; f.Route != 2 || f.Method != "GET" || f.Path != "/bin" || !strings.Contains(f.Error, "bodyBase64") {
+		t.Errorf("route finding = %+v", f)
+	}
+	if f := result.Findings[0]; f.Route != 0 || !strings.Contains(f.Error, "/ok") {
+		t.Errorf("duplicate route finding = %+v", f)
+	}
+
+	out.Reset()
+	failing := func() (inputType, error) { return inputType{}, errors.New("config.json: unexpected EOF") }
+	if code := runConfigCheck(&out, failing, false); code != 1 || !strings.Contains(out.String(), "unexpected EOF") {
+		t.Errorf("load error: exit code %d, output %q", code, out.String())
+	}
+}
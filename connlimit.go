@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"net"
+	"sync"
+)
+
+// perIPListener caps the number of open connections per remote IP. A
+// connection over the limit is closed right after it is accepted, so the
+// client sees it reset before any request is read.
+type perIPListener struct {
+	net.Listener
+	max int
+
+	mu    sync.Mutex
+	conns map[string]int // open connections by remote IP
+}
+
+// limitConnsPerIP wraps ln so each remote IP may hold at most max
+// connections at a time; max <= 0 leaves ln as it is.
+func limitConnsPerIP(ln net.Listener, max int) net.Listener {
+	if max <= 0 {
+		return ln
+	}
+	return &perIPListener{Listener: ln, max: max, conns: map[string]int{}}
+}
+
+// Accept implements net.Listener.
+func (l *perIPListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := c.RemoteAddr().String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+
+		l.mu.Lock()
+		if l.conns[ip] >= l.max {
+			l.mu.Unlock()
+			log.Printf("refused connection from %s: already %d open (-max-conns-per-ip)", ip, l.max)
+			_ = c.Close()
+			continue
+		}
+		l.conns[ip]++
+		l.mu.Unlock()
+		return &trackedConn{Conn: c, release: func() { l.release(ip) }}, nil
+	}
+}
+
+// release forgets a closed connection of ip.
+func (l *perIPListener) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[ip]--; l.conns[ip] <= 0 {
+		delete(l.conns, ip)
+	}
+}
+
+// trackedConn calls release once when it is closed.
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+// Close implements net.Conn.
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
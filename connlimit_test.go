@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLimitConnsPerIP(t *testing.T) {
+	quietLog(t)
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := limitConnsPerIP(inner, 2)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	dial := func() net.Conn {
+		t.Helper()
+		c, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	// closedByServer reports whether the server closed c without accepting it.
+	closedByServer := func(c net.Conn) bool {
+		c.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		_, err := c.Read(make([]byte, 1))
+		return err == io.EOF || (err != nil && !isTimeout(err))
+	}
+
+	first, second := dial(), dial()
+	serverFirst, serverSecond := <-accepted, <-accepted
+	defer serverSecond.Close()
+	if closedByServer(first) || closedByServer(second) {
+		t.Fatal("connections under the limit were closed")
+	}
+
+	third := dial()
+	if !closedByServer(third) {
+		t.Error("third connection from the same IP was not refused")
+	}
+	if len(accepted) != 0 {
+		t.Error("third connection was handed to the server")
+	}
+
+	// Closing a connection frees its slot.
+	serverFirst.Close()
+	dial()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(2 * time.Second):
+		t.Error("new connection not accepted after one was closed")
+	}
+}
+
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+func TestLimitConnsPerIPDisabled(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inner.Close()
+	if ln := limitConnsPerIP(inner, 0); ln != inner {
+		t.Error("max 0 wrapped the listener")
+	}
+}
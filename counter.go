@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// counterType keeps an in-memory counter per value of a path param, bumped
+// on every call and returned in the response body.
+//
+// Example JSON fragment, for POST /posts/{id}/like:
+//
+//	"counter": { "param": "id", "field": "likes" }
+//
+// The first like of post 7 answers {"likes": 1}, the second {"likes": 2},
+// while post 8 starts again at 1. A counter not bumped for counterIdleTTL is
+// dropped and starts again at 0.
+type counterType struct {
+	Param string `json:"param"` // Path param the counters are keyed by
+	Field string `json:"field"` // Dot-separated body field receiving the count (default: "count")
+	Step  int64  `json:"step"`  // Added on every call (default: 1; negative counts down)
+}
+
+// counterIdleTTL is how long a counter outlives its last bump.
+const counterIdleTTL = 24 * time.Hour
+
+// counters holds the counts of one route's counter block.
+type counters struct {
+	def counterType
+
+	mu     sync.Mutex
+	counts *idleMap[string, int64]
+}
+
+// newCounters validates a counter block and applies its defaults.
+func newCounters(def counterType, path string) (*counters, error) {
+	if def.Param == "" {
+		return nil, fmt.Errorf("counter.param is required")
+	}
+	if !pathHasParam(path, def.Param) {
+		return nil, fmt.Errorf("counter.param %q is not a param of the path", def.Param)
+	}
+	if def.Field == "" {
+		def.Field = "count"
+	}
+	if def.Step == 0 {
+		def.Step = 1
+	}
+	return &counters{def: def, counts: newIdleMap[string, int64](counterIdleTTL)}, nil
+}
+
+// pathHasParam reports whether the route path has a {name} (or {name:regex})
+// segment.
+func pathHasParam(path, name string) bool {
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		if m[1] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// next bumps the request's counter and returns its new value.
+func (c *counters) next(r *http.Request) int64 {
+	key := chi.URLParam(r, c.def.Param)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	count, _ := c.counts.get(key, now)
+	count += c.def.Step
+	c.counts.set(key, count, now)
+	return count
+}
+
+// apply bumps the request's counter and returns a copy of body with the new
+// value in the counter field. A body that is not an object is replaced by
+// {field: count}.
+func (c *counters) apply(r *http.Request, body any) (any, error) {
+	count := c.next(r)
+	if _, ok := body.(map[string]any); !ok {
+		out := map[string]any{}
+		setNestedField(out, c.def.Field, count)
+		return out, nil
+	}
+	out, err := cloneJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	setNestedField(out.(map[string]any), c.def.Field, count)
+	return out, nil
+}
+
+// setNestedField sets a dot-separated field in obj, creating (or replacing
+// non-object) intermediate objects as needed.
+func setNestedField(obj map[string]any, path string, value any) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := obj[key].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			obj[key] = child
+		}
+		obj = child
+	}
+	obj[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "POST", "path": "/posts/{id}/like", "counter": {"param": "id", "field": "stats.likes"}, "response": {"status": 200, "body": {"ok": true}}},
+		{"method": "POST", "path": "/posts/{id}/unlike", "counter": {"param": "id", "step": -1}, "response": {"status": 200}}
+	]}`)
+
+	for i, tt := range []struct{ path, want string }{
+		{"/posts/7/like", `{"ok":true,"stats":{"likes":1}}`},
+		{"/posts/7/like", `{"ok":true,"stats":{"likes":2}}`},
+		{"/posts/8/like", `{"ok":true,"stats":{"likes":1}}`}, // a different id starts fresh
+		{"/posts/7/like", `{"ok":true,"stats":{"likes":3}}`},
+		{"/posts/7/unlike", `{"count":-1}`}, // counters are per route
+	} {
+		if _, body := post(t, srv.URL+tt.path, ""); body != tt.want {
+			t.Errorf("call %d, %s: body = %s, want %s", i+1, tt.path, body, tt.want)
+		}
+	}
+}
+
+func TestNewCountersErrors(t *testing.T) {
+	for _, tt := range []struct {
+		def  counterType
+		path string
+		want string
+	}{
+		{counterType{}, "/posts/{id}", "counter.param is required"},
+		{counterType{Param: "slug"}, "/posts/{id}", `"slug" is not a param`},
+		{counterType{Param: "i"}, "/posts/{id}", `"i" is not a param`},
+	} {
+		if _, err := newCounters(tt.def, tt.path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v on %s: err = %v, want %q", tt.def, tt.path, err, tt.want)
+		}
+	}
+	if _, err := newCounters(counterType{Param: "id"}, "/posts/{id:[0-9]+}"); err != nil {
+		t.Errorf("regex param: %v", err)
+	}
+}
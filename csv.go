@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"unicode/utf8"
+)
+
+// loadCSVBody reads a CSV file into a JSON array of objects, using the
+// header row as keys. Every value is kept as a string.
+//
+// delimiter must be a single character; empty means ",".
+func loadCSVBody(path, delimiter string) ([]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	if delimiter != "" {
+		comma, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) {
+			return nil, fmt.Errorf("csv delimiter must be a single character, got %q", delimiter)
+		}
+		reader.Comma = comma
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []any{}, nil
+	}
+
+	header := records[0]
+	rows := make([]any, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]any, len(header))
+		for i, key := range header {
+			row[key] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBodyCSV(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "countries.csv")
+	if err := os.WriteFile(path, []byte("code;name\nFR;France\nJP;\"Japan; Nippon\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, _ := json.Marshal(map[string]any{"routes": []any{map[string]any{
+		"method": "GET", "path": "/countries",
+		"response": map[string]any{"status": 200, "bodyCsv": path, "delimiter": ";"},
+	}}})
+	srv := newTestServer(t, string(config))
+
+	_, body := get(t, srv.URL+"/countries")
+	var rows []map[string]string
+	if err := json.Unmarshal([]byte(body), &rows); err != nil {
+		t.Fatalf("body is not an array of objects: %v\n%s", err, body)
+	}
+	want := []map[string]string{
+		{"code": "FR", "name": "France"},
+		{"code": "JP", "name": "Japan; Nippon"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+func TestLoadCSVBodyErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte("a,b\n1,2,3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCSVBody(path, ""); err == nil {
+		t.Error("loadCSVBody accepted a row with more fields than the header")
+	}
+	if _, err := loadCSVBody(path, "::"); err == nil || !strings.Contains(err.Error(), "single character") {
+		t.Errorf("multi-character delimiter: err = %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// writeCurlScript exports the config as a shell script with one curl
+// command per method+path, to share reproduction steps.
+//
+// Requests go to $BASE_URL, defaulting to baseURL. Path params are filled
+// with "1". A route's example request (query, headers and body) is sent when
+// it has one; otherwise required query params get "value", and POST, PUT and
+// PATCH requests send an empty JSON object as their body.
+func writeCurlScript(path string, input inputType, baseURL string) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Generated by mocker: one request per configured route.\n")
+	fmt.Fprintf(&b, "BASE_URL=\"${BASE_URL:-%s}\"\n\n", strings.TrimSuffix(baseURL, "/"))
+
+	seen := map[string]bool{}
+	for _, route := range input.Routes {
+		method := normalizeMethod(route.Method)
+		full := input.fullPath(route.Path)
+		if seen[method+" "+full] {
+			continue
+		}
+		seen[method+" "+full] = true
+		b.WriteString(curlCommand(method, full, route.RequiredQuery, route.Example))
+		b.WriteString("\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o755)
+}
+
+// curlCommand renders the curl invocation for one route.
+func curlCommand(method, path string, requiredQuery []string, example *exampleType) string {
+	target := pathParamPattern.ReplaceAllString(path, "1")
+	if example != nil {
+		return exampleCurlCommand(method, target, example)
+	}
+	if len(requiredQuery) > 0 {
+		pairs := make([]string, 0, len(requiredQuery))
+		for _, name := range requiredQuery {
+			pairs = append(pairs, name+"=value")
+		}
+		target += "?" + strings.Join(pairs, "&")
+	}
+
+	args := []string{"curl", "-sS", "-X", method}
+	if method == http.MethodHead {
+		args = []string{"curl", "-sS", "-I"}
+	}
+	if slices.Contains([]string{http.MethodPost, http.MethodPut, http.MethodPatch}, method) {
+		args = append(args, "-H", shellQuote("Content-Type: application/json"), "-d", shellQuote("{}"))
+	}
+	args = append(args, `"$BASE_URL"`+shellQuote(target))
+	return strings.Join(args, " ")
+}
+
+// exampleCurlCommand renders the curl invocation sending a route's example
+// request to target.
+func exampleCurlCommand(method, target string, example *exampleType) string {
+	if example.Query != "" {
+		target += "?" + example.Query
+	}
+	args := []string{"curl", "-sS", "-X", method}
+	if method == http.MethodHead {
+		args = []string{"curl", "-sS", "-I"}
+	}
+	names := make([]string, 0, len(example.Headers))
+	for name := range example.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-H", shellQuote(name+": "+example.Headers[name]))
+	}
+	if example.Body != nil {
+		data, isString := example.Body.(string)
+		if !isString {
+			raw, _ := json.Marshal(example.Body)
+			data = string(raw)
+			if _, ok := example.Headers["Content-Type"]; !ok {
+				args = append(args, "-H", shellQuote("Content-Type: application/json"))
+			}
+		}
+		args = append(args, "--data-raw", shellQuote(data))
+	}
+	args = append(args, `"$BASE_URL"`+shellQuote(target))
+	return strings.Join(args, " ")
+}
+
+// shellQuote single-quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
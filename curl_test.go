@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteCurlScript(t *testing.T) {
+	input := parseInput(t, `{"basePath": "/api", "routes": [
+		{"method": "GET", "path": "/users/{id:[0-9]+}", "response": {"status": 200}},
+		{"method": "get", "path": "/users/{id:[0-9]+}", "response": {"status": 404}},
+		{"method": "GET", "path": "/search", "requiredQuery": ["q", "page"], "response": {"status": 200}},
+		{"method": "POST", "path": "/users", "response": {"status": 201}},
+		{"method": "PATCH", "path": "/users/{id}", "example": {"headers": {"X-Role": "admin"}, "body": {"name": "O'Neil"}}, "response": {"status": 200}},
+		{"method": "HEAD", "path": "/health", "response": {"status": 200}}
+	]}`)
+	path := filepath.Join(t.TempDir(), "requests.sh")
+	if err := writeCurlScript(path, input, "http://localhost:8080/"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("script is not executable: %v", info.Mode())
+	}
+
+	want := `#!/bin/sh
+# Generated by mocker: one request per configured route.
+BASE_URL="${BASE_URL:-http://localhost:8080}"
+
+curl -sS -X GET "$BASE_URL"'/api/users/1'
+curl -sS -X GET "$BASE_URL"'/api/search?q=value&page=value'
+curl -sS -X POST -H 'Content-Type: application/json' -d '{}' "$BASE_URL"'/api/users'
+curl -sS -X PATCH -H 'X-Role: admin' -H 'Content-Type: application/json' --data-raw '{"name":"O'\''Neil"}' "$BASE_URL"'/api/users/1'
+curl -sS -I "$BASE_URL"'/api/health'
+`
+	if got := string(data); got != want {
+		t.Errorf("script:\n%s\nwant:\n%s", got, want)
+	}
+	if n := strings.Count(string(data), "curl "); n != 5 {
+		t.Errorf("%d curl lines, want one per method+path (5)", n)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// downloadType turns a response into a file download that makes browsers
+// show a save dialog.
+//
+// The bytes come from File when set, otherwise from bodyBase64, otherwise
+// from body (a string body is sent as is, anything else as JSON).
+//
+// Example JSON fragment:
+//
+//	"response": {
+//	  "status": 200,
+//	  "download": { "filename": "report.csv", "file": "./fixtures/report.csv" }
+//	}
+type downloadType struct {
+	Filename string `json:"filename"` // Name suggested to the client in Content-Disposition
+	File     string `json:"file"`     // Optional path of a file whose content is served
+}
+
+// prepareDownload loads the download's bytes into p.raw and adds the
+// Content-Disposition header. The Content-Type defaults to the one matching
+// the filename's extension.
+func prepareDownload(p *preparedResponse) error {
+	d := p.Download
+	if d.Filename == "" {
+		return fmt.Errorf("download.filename is required")
+	}
+
+	switch {
+	case d.File != "":
+		data, err := os.ReadFile(d.File)
+		if err != nil {
+			return fmt.Errorf("download.file: %w", err)
+		}
+		p.raw = data
+	case p.raw != nil:
+		// bodyBase64 was already decoded.
+	default:
+		if s, ok := p.Body.(string); ok {
+			p.raw = []byte(s)
+		} else {
+			data, err := json.Marshal(p.Body)
+			if err != nil {
+				return fmt.Errorf("download body: %w", err)
+			}
+			p.raw = data
+		}
+	}
+
+	if p.ContentType == "" {
+		p.ContentType = mime.TypeByExtension(filepath.Ext(d.Filename))
+	}
+	// Copy the headers so the config map is not modified; a configured
+	// Content-Disposition wins.
+	headers := maps.Clone(p.Headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	for name := range headers {
+		if http.CanonicalHeaderKey(name) == "Content-Disposition" {
+			p.Headers = headers
+			return nil
+		}
+	}
+	headers["Content-Disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": d.Filename})
+	p.Headers = headers
+	return nil
+}
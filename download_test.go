@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report.csv")
+	if err := os.WriteFile(file, []byte("id,name\n1,Ada\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/report", "response": {"status": 200, "download": {"filename": "report.csv", "file": "`+filepath.ToSlash(file)+`"}}},
+		{"method": "GET", "path": "/logo", "response": {"status": 200, "bodyBase64": "iVBORw0K", "download": {"filename": "logo.png"}}},
+		{"method": "GET", "path": "/notes", "response": {"status": 200, "body": "hello", "download": {"filename": "notes.txt"}}},
+		{"method": "GET", "path": "/data", "response": {"status": 200, "body": {"a": 1}, "download": {"filename": "data.json"}}}
+	]}`)
+
+	for _, tt := range []struct {
+		path, disposition, contentType, body string
+	}{
+		// .csv and .txt types come from the system mime tables, so they are not checked.
+		{"/report", `attachment; filename=report.csv`, "", "id,name\n1,Ada\n"},
+		{"/logo", `attachment; filename=logo.png`, "image/png", "\x89PNG\r\n"},
+		{"/notes", `attachment; filename=notes.txt`, "", "hello"},
+		{"/data", `attachment; filename=data.json`, "application/json", `{"a":1}`},
+	} {
+		res, body := get(t, srv.URL+tt.path)
+		if got := res.Header.Get("Content-Disposition"); got != tt.disposition {
+			t.Errorf("%s: Content-Disposition = %q, want %q", tt.path, got, tt.disposition)
+		}
+		if got := res.Header.Get("Content-Type"); tt.contentType != "" && got != tt.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.path, got, tt.contentType)
+		}
+		if body != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestDownloadNeedsFilename(t *testing.T) {
+	_, err := BuildRouter(parseInput(t, `{"routes": [{"method": "GET", "path": "/report", "response": {"status": 200, "body": "x", "download": {}}}]}`))
+	if err == nil {
+		t.Fatal("BuildRouter accepted a download without filename")
+	}
+}
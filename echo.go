@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// prepareMerge parses every string in an echoWithMerge object that contains
+// template actions, so values like "{{nextId}}" or "{{now}}" are generated
+// per request. Other values are kept as they are.
+func prepareMerge(v any) (any, error) {
+	switch node := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for key, value := range node {
+			prepared, err := prepareMerge(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			out[key] = prepared
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(node))
+		for i, value := range node {
+			prepared, err := prepareMerge(value)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			out[i] = prepared
+		}
+		return out, nil
+	case string:
+		if !strings.Contains(node, "{{") {
+			return node, nil
+		}
+		return parseBodyTemplate("echoWithMerge", node)
+	default:
+		return v, nil
+	}
+}
+
+// renderMerge returns a copy of a prepared echoWithMerge value with its
+// templates executed for the request.
+func renderMerge(v any, data *templateData) (any, error) {
+	switch node := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for key, value := range node {
+			rendered, err := renderMerge(value, data)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = rendered
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(node))
+		for i, value := range node {
+			rendered, err := renderMerge(value, data)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	case *template.Template:
+		out, err := executeTemplate(node, data)
+		return string(out), err
+	default:
+		return v, nil
+	}
+}
+
+// mergeJSON deep-merges override into base: objects are merged key by key
+// and any other override value replaces the base value.
+func mergeJSON(base, override any) any {
+	baseObj, baseIsObj := base.(map[string]any)
+	overrideObj, overrideIsObj := override.(map[string]any)
+	if !baseIsObj || !overrideIsObj {
+		return override
+	}
+	for key, value := range overrideObj {
+		baseObj[key] = mergeJSON(baseObj[key], value)
+	}
+	return baseObj
+}
+
+// serveEchoWithMerge responds with the posted JSON object deep-merged with
+// the response's echoWithMerge object. An empty body is treated as {}.
+func (h *routeHandler) serveEchoWithMerge(w http.ResponseWriter, r *http.Request, res preparedResponse) error {
+	raw, err := readRequestBody(r)
+	if err != nil {
+		return err
+	}
+	posted := map[string]any{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &posted); err != nil {
+			return respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "request body must be a JSON object"})
+		}
+	}
+
+	data, err := newTemplateData(r, h.state)
+	if err != nil {
+		return err
+	}
+	merge, err := renderMerge(res.merge, data)
+	if err != nil {
+		return fmt.Errorf("rendering echoWithMerge: %w", err)
+	}
+	return respondWithJSON(w, res.Status, mergeJSON(posted, merge))
+}
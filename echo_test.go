@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEchoWithMerge(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "POST", "path": "/users", "response": {"status": 201,
+		"echoWithMerge": {"id": "{{nextId}}", "createdAt": "{{now}}", "profile": {"verified": false}}}}]}`)
+
+	res, body := post(t, srv.URL+"/users", `{"name": "Ada", "profile": {"lang": "en", "verified": true}}`)
+	if res.StatusCode != 201 {
+		t.Errorf("status = %d, want 201", res.StatusCode)
+	}
+	var got struct {
+		ID        string         `json:"id"`
+		Name      string         `json:"name"`
+		CreatedAt string         `json:"createdAt"`
+		Profile   map[string]any `json:"profile"`
+	}
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("body %s: %v", body, err)
+	}
+	if got.ID != "1" || got.Name != "Ada" {
+		t.Errorf("id, name = %q, %q; want 1, Ada", got.ID, got.Name)
+	}
+	if _, err := time.Parse(time.RFC3339, got.CreatedAt); err != nil {
+		t.Errorf("createdAt %q is not RFC 3339: %v", got.CreatedAt, err)
+	}
+	if got.Profile["lang"] != "en" || got.Profile["verified"] != false {
+		t.Errorf("profile = %v, want the posted lang deep-merged with verified=false", got.Profile)
+	}
+
+	if res, _ := post(t, srv.URL+"/users", `["not", "an", "object"]`); res.StatusCode != 400 {
+		t.Errorf("array body: status = %d, want 400", res.StatusCode)
+	}
+}
+
+func TestEchoWithMergeNotObject(t *testing.T) {
+	_, err := BuildRouter(parseInput(t, `{"routes": [{"method": "POST", "path": "/x", "response": {"echoWithMerge": [1]}}]}`))
+	if err == nil {
+		t.Error("BuildRouter accepted a non-object echoWithMerge")
+	}
+}
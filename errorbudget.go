@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// errorBudgetType makes a set share of a route's requests fail, e.g. to test
+// SLO alerting.
+//
+// Failures are spread evenly rather than drawn at random: with 5 percent,
+// exactly one request in every 20 fails, so the error rate matches the budget
+// over any long enough run.
+//
+// Example JSON fragment:
+//
+//	"errorBudget": { "percent": 5, "response": { "status": 503, "body": { "error": "unavailable" } } }
+type errorBudgetType struct {
+	Percent  float64   `json:"percent"`  // Share of requests failing, from 0 to 100
+	Response *response `json:"response"` // Served to failing requests (default: 500 with an error body; status defaults to 500 too)
+}
+
+// defaultErrorBudgetResponse is served by failing requests when errorBudget
+// has no response.
+var defaultErrorBudgetResponse = response{
+	Status: http.StatusInternalServerError,
+	Body:   map[string]string{"error": "simulated failure (errorBudget)"},
+}
+
+// preparedErrorBudget is a validated errorBudgetType with its request count.
+type preparedErrorBudget struct {
+	percent float64
+	failure preparedResponse
+
+	mu    sync.Mutex
+	calls int64
+}
+
+// prepareErrorBudget validates an errorBudget block and prepares its response.
+func prepareErrorBudget(def *errorBudgetType) (*preparedErrorBudget, error) {
+	if def.Percent < 0 || def.Percent > 100 {
+		return nil, fmt.Errorf("errorBudget.percent must be between 0 and 100, got %v", def.Percent)
+	}
+	res := defaultErrorBudgetResponse
+	if def.Response != nil {
+		res = *def.Response
+		if res.Status == 0 {
+			res.Status = http.StatusInternalServerError
+		}
+	}
+	failure, err := prepareResponse(res)
+	if err != nil {
+		return nil, fmt.Errorf("errorBudget.response: %w", err)
+	}
+	return &preparedErrorBudget{percent: def.Percent, failure: failure}, nil
+}
+
+// fail counts a request and reports whether it should fail: request n fails
+// when it takes the number of failures due after n requests, n*percent/100,
+// to the next whole number.
+func (b *preparedErrorBudget) fail() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	return b.due(b.calls) > b.due(b.calls-1)
+}
+
+// due returns the whole number of failures owed after n requests.
+func (b *preparedErrorBudget) due(n int64) int64 {
+	return int64(float64(n) * b.percent / 100)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestErrorBudget(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/default", "errorBudget": {"percent": 25}, "response": {"status": 200}},
+		{"method": "GET", "path": "/custom", "errorBudget": {"percent": 50, "response": {"status": 503, "body": "down"}}, "response": {"status": 200}},
+		{"method": "GET", "path": "/nostatus", "errorBudget": {"percent": 100, "response": {"body": "boom"}}, "response": {"status": 200}}
+	]}`)
+
+	statuses := func(path string, n int) string {
+		var got []string
+		for i := 0; i < n; i++ {
+			res, _ := get(t, srv.URL+path)
+			got = append(got, fmt.Sprint(res.StatusCode))
+		}
+		return strings.Join(got, " ")
+	}
+	if got, want := statuses("/default", 8), "200 200 200 500 200 200 200 500"; got != want {
+		t.Errorf("25%%: statuses = %s, want %s", got, want)
+	}
+	if got, want := statuses("/custom", 4), "200 503 200 503"; got != want {
+		t.Errorf("50%%: statuses = %s, want %s", got, want)
+	}
+	if res, body := get(t, srv.URL+"/nostatus"); res.StatusCode != 500 || body != `"boom"` {
+		t.Errorf("response without status: got %d %s, want 500 \"boom\"", res.StatusCode, body)
+	}
+}
+
+func TestErrorBudgetPercentRange(t *testing.T) {
+	for _, percent := range []string{"-1", "101"} {
+		input := parseInput(t, `{"routes": [{"method": "GET", "path": "/a", "errorBudget": {"percent": `+percent+`}, "response": {"status": 200}}]}`)
+		if _, err := BuildRouter(input); err == nil || !strings.Contains(err.Error(), "errorBudget.percent") {
+			t.Errorf("percent %s: err = %v, want a range error", percent, err)
+		}
+	}
+}
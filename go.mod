@@ -2,4 +2,9 @@ module github.com/Yuddhaa/mocker
 
 go 1.24.5
 
-require github.com/go-chi/chi/v5 v5.2.3
+require (
+	github.com/go-chi/chi/v5 v5.2.3
+	go.starlark.net v0.0.0-20250417143717-f57e51f710eb
+	golang.org/x/sys v0.35.0
+	golang.org/x/text v0.28.0
+)
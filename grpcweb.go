@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// routeTypeGRPCWeb is the route type serving gRPC-Web unary calls.
+const routeTypeGRPCWeb = "grpcweb"
+
+// gRPC-Web frame flags: data frames carry messages, trailer frames carry
+// the grpc-status and grpc-message of the call.
+const (
+	grpcDataFrame    byte = 0x00
+	grpcTrailerFrame byte = 0x80
+)
+
+// validateGRPCWeb checks a grpcweb route: gRPC-Web calls are always POSTs to
+// /package.Service/Method.
+func validateGRPCWeb(route routesType) error {
+	if route.Method != http.MethodPost {
+		return fmt.Errorf("grpcweb routes must use POST")
+	}
+	return nil
+}
+
+// serveGRPCWeb answers a gRPC-Web unary call. The request must hold exactly
+// one length-prefixed message; the response carries the bodyBase64 message
+// (unless grpcStatus is an error) followed by the trailer frame.
+//
+// Both the binary (application/grpc-web+proto) and the base64 text
+// (application/grpc-web-text) encodings are supported.
+func serveGRPCWeb(w http.ResponseWriter, r *http.Request, res preparedResponse) error {
+	contentType := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/grpc-web") {
+		return respondWithJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "expected a gRPC-Web request"})
+	}
+	text := strings.HasPrefix(contentType, "application/grpc-web-text")
+
+	var body io.Reader = r.Body
+	if text {
+		body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid gRPC-Web body: " + err.Error()})
+	}
+	if err := checkGRPCFrame(raw); err != nil {
+		return respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var out bytes.Buffer
+	if res.GRPCStatus == 0 {
+		writeGRPCFrame(&out, grpcDataFrame, res.raw)
+	}
+	trailer := fmt.Sprintf("grpc-status:%d\r\ngrpc-message:%s\r\n", res.GRPCStatus, url.PathEscape(res.GRPCMessage))
+	writeGRPCFrame(&out, grpcTrailerFrame, []byte(trailer))
+
+	payload := out.Bytes()
+	if text {
+		payload = []byte(base64.StdEncoding.EncodeToString(payload))
+	}
+	w.Header().Set("Content-Type", contentType)
+	status := res.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return respondWithBytes(w, status, contentType, payload)
+}
+
+// checkGRPCFrame verifies raw is a single uncompressed data frame whose
+// length prefix matches its payload.
+func checkGRPCFrame(raw []byte) error {
+	if len(raw) < 5 {
+		return fmt.Errorf("gRPC-Web frame too short: %d bytes", len(raw))
+	}
+	if raw[0] != grpcDataFrame {
+		return fmt.Errorf("unsupported gRPC-Web frame flag 0x%02x", raw[0])
+	}
+	if n := binary.BigEndian.Uint32(raw[1:5]); int(n) != len(raw)-5 {
+		return fmt.Errorf("gRPC-Web frame length %d does not match %d payload bytes", n, len(raw)-5)
+	}
+	return nil
+}
+
+// writeGRPCFrame appends a frame: flag byte, 4-byte big-endian length, data.
+func writeGRPCFrame(buf *bytes.Buffer, flag byte, data []byte) {
+	var header [5]byte
+	header[0] = flag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	buf.Write(header[:])
+	buf.Write(data)
+}
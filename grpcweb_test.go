@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// grpcFrame returns a gRPC-Web frame with the given flag and data.
+func grpcFrame(flag byte, data string) []byte {
+	var buf bytes.Buffer
+	writeGRPCFrame(&buf, flag, []byte(data))
+	return buf.Bytes()
+}
+
+func TestGRPCWeb(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "POST", "path": "/pkg.Users/Get", "type": "grpcweb", "response": {"bodyBase64": "`+base64.StdEncoding.EncodeToString([]byte("\x0a\x03Ada"))+`"}},
+		{"method": "POST", "path": "/pkg.Users/Delete", "type": "grpcweb", "response": {"grpcStatus": 7, "grpcMessage": "not allowed"}}
+	]}`)
+
+	call := func(path, contentType string, body []byte) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		res, out := do(t, req)
+		return res, []byte(out)
+	}
+
+	request := grpcFrame(grpcDataFrame, "\x08\x01")
+	res, out := call("/pkg.Users/Get", "application/grpc-web+proto", request)
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "application/grpc-web+proto" {
+		t.Fatalf("status %d, Content-Type %q", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	want := append(grpcFrame(grpcDataFrame, "\x0a\x03Ada"), grpcFrame(grpcTrailerFrame, "grpc-status:0\r\ngrpc-message:\r\n")...)
+	if !bytes.Equal(out, want) {
+		t.Errorf("response frames = %q, want %q", out, want)
+	}
+
+	res, out = call("/pkg.Users/Get", "application/grpc-web-text", []byte(base64.StdEncoding.EncodeToString(request)))
+	decoded, err := base64.StdEncoding.DecodeString(string(out))
+	if err != nil || !bytes.Equal(decoded, want) {
+		t.Errorf("grpc-web-text response = %q (%v), want base64 of %q", out, err, want)
+	}
+
+	_, out = call("/pkg.Users/Delete", "application/grpc-web+proto", request)
+	if out[0] != grpcTrailerFrame || binary.BigEndian.Uint32(out[1:5]) != uint32(len(out)-5) {
+		t.Fatalf("error call: want a single trailer frame, got %q", out)
+	}
+	if trailer := string(out[5:]); !strings.Contains(trailer, "grpc-status:7\r\n") || !strings.Contains(trailer, "grpc-message:not%20allowed\r\n") {
+		t.Errorf("error call trailer = %q", trailer)
+	}
+
+	for _, tt := range []struct {
+		name, contentType string
+		body              []byte
+		status            int
+	}{
+		{"not gRPC-Web", "application/json", request, http.StatusUnsupportedMediaType},
+		{"short frame", "application/grpc-web+proto", []byte{0, 0}, http.StatusBadRequest},
+		{"bad length", "application/grpc-web+proto", append(grpcFrame(grpcDataFrame, "ab"), 'c'), http.StatusBadRequest},
+		{"compressed", "application/grpc-web+proto", grpcFrame(0x01, "ab"), http.StatusBadRequest},
+	} {
+		if res, _ := call("/pkg.Users/Get", tt.contentType, tt.body); res.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, res.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestValidateGRPCWeb(t *testing.T) {
+	_, err := BuildRouter(parseInput(t, `{"routes": [{"method": "GET", "path": "/pkg.Users/Get", "type": "grpcweb", "response": {}}]}`))
+	if err == nil || !strings.Contains(err.Error(), "POST") {
+		t.Errorf("err = %v, want grpcweb routes to require POST", err)
+	}
+}
@@ -0,0 +1,584 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// routeHandler serves a single configured route and keeps any per-route
+// state (such as the call counter) that must survive across requests.
+type routeHandler struct {
+	route     routesType
+	responses []preparedResponse // sorted by AfterCalls, ascending
+	chaos     []preparedResponse // picked at random instead of responses when non-empty
+	perClient *perClientPicker   // non-nil when each client gets its own response
+	counters  *counters          // non-nil when the route has a counter block
+	rateLimit *slidingWindow     // non-nil when the route has a rateLimit block
+	state     *routerState       // state shared by all routes of the router
+
+	idempotency *idempotencyCache    // non-nil when Idempotency-Key replay is enabled
+	schedule    *preparedSchedule    // non-nil when the route has opening hours
+	errorBudget *preparedErrorBudget // non-nil when the route has an errorBudget
+
+	mu    sync.Mutex
+	calls int // number of requests served so far
+
+	rampCalls atomic.Int64 // requests seen by rampDelay
+}
+
+// preparedResponse is a response with everything that can be computed at
+// startup already done.
+type preparedResponse struct {
+	response
+	raw    []byte                        // decoded BodyBase64; nil when Body should be sent as JSON
+	tmpl   *template.Template            // parsed BodyTemplate; nil when not set
+	script *bodyScript                   // compiled BodyScript; nil when not set
+	header map[string]*template.Template // parsed templated Headers values; nil when none
+	merge  any                           // prepared EchoWithMerge; nil when not set
+
+	transformBody  any                // Body with templates prepared for Transform.Templates
+	transformMerge any                // prepared Transform.Merge; nil when not set
+	files          *fileSequence      // loaded BodyFiles; nil when not set
+	localized      *preparedLocalized // prepared Localized; nil when not set
+}
+
+// routerState is the state shared between the routes of one router, such as
+// the in-memory store. It lives as long as the router.
+type routerState struct {
+	store     *memoryStore
+	nextID    atomic.Int64    // backs the nextId template function
+	sequences sequenceTracker // progress of sequence flows per session
+
+	started time.Time        // when the router was built; the start of failBetweenMs windows
+	now     func() time.Time // clock, replaceable in tests
+}
+
+// newRouterState returns empty shared state.
+func newRouterState() *routerState {
+	return &routerState{store: newMemoryStore(), started: time.Now(), now: time.Now}
+}
+
+// uptime returns the time elapsed since the router was built.
+func (s *routerState) uptime() time.Duration {
+	return s.now().Sub(s.started)
+}
+
+// newRouteHandler validates a route and prepares its responses.
+//
+// When the route defines a "responses" array it is used instead of the single
+// "response" object.
+func newRouteHandler(route routesType, state *routerState) (*routeHandler, error) {
+	defs := route.Responses
+	if len(defs) == 0 {
+		defs = []response{route.Response}
+	}
+	if route.Store != nil {
+		if err := validateStore(route.Store); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+	}
+
+	switch route.Type {
+	case "":
+	case routeTypeGRPCWeb:
+		if err := validateGRPCWeb(route); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+	case routeTypeJSONRPC:
+		if err := validateJSONRPC(route); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s %s: unknown route type %q", route.Method, route.Path, route.Type)
+	}
+	if route.RampDelay != nil {
+		if err := validateRampDelay(route.RampDelay); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+	}
+	if route.Latency != nil {
+		if err := validateLatency(route.Latency); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+	}
+	if len(route.FailBetweenMs) > 0 {
+		if err := validateFailWindow(route.FailBetweenMs); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+	}
+	if route.Match != nil {
+		if err := route.Match.compile(); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+	}
+	if route.Sequence != nil {
+		seq := *route.Sequence // copied so the default header is not written back to the config
+		if err := validateSequence(&seq); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		route.Sequence = &seq
+	}
+	if route.Capture != nil {
+		if route.Capture.Store == "" {
+			return nil, fmt.Errorf("%s %s: capture.store is required", route.Method, route.Path)
+		}
+		for field, path := range route.Capture.Fields {
+			if _, err := parseJSONPath(path); err != nil {
+				return nil, fmt.Errorf("%s %s: invalid capture.fields.%s: %w", route.Method, route.Path, field, err)
+			}
+		}
+	}
+
+	h := &routeHandler{route: route, state: state}
+	if route.Idempotency != nil {
+		cache, err := newIdempotencyCache(route.Idempotency)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		h.idempotency = cache
+	}
+	if route.ErrorBudget != nil {
+		budget, err := prepareErrorBudget(route.ErrorBudget)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		h.errorBudget = budget
+	}
+	if route.Schedule != nil {
+		schedule, err := prepareSchedule(route.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		h.schedule = schedule
+	}
+	for _, def := range defs {
+		p, err := prepareResponse(def)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		if p.raw == nil && p.tmpl == nil && p.script == nil {
+			for param, path := range route.OverridableFields {
+				if _, ok := lookupPath(p.Body, path); !ok {
+					return nil, fmt.Errorf("%s %s: overridable field %q (query %q) not found in response body", route.Method, route.Path, path, param)
+				}
+			}
+		}
+		h.responses = append(h.responses, p)
+	}
+	sort.SliceStable(h.responses, func(i, j int) bool {
+		return h.responses[i].AfterCalls < h.responses[j].AfterCalls
+	})
+
+	if route.RateLimit != nil {
+		window, err := newSlidingWindow(route.RateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		h.rateLimit = window
+	}
+	if route.Counter != nil {
+		c, err := newCounters(*route.Counter, route.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		h.counters = c
+	}
+	if route.PerClient != nil {
+		picker, err := newPerClientPicker(route.PerClient)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		h.perClient = picker
+	}
+
+	for _, def := range route.ChaosResponses {
+		p, err := prepareResponse(def)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: chaosResponses: %w", route.Method, route.Path, err)
+		}
+		h.chaos = append(h.chaos, p)
+	}
+	return h, nil
+}
+
+// prepareResponse decodes binary bodies, loads CSV files and parses templates
+// once up front so a bad config fails at startup rather than on the first
+// request.
+func prepareResponse(def response) (preparedResponse, error) {
+	p := preparedResponse{response: def}
+	if def.BodyBase64 != "" {
+		raw, err := base64.StdEncoding.DecodeString(def.BodyBase64)
+		if err != nil {
+			return p, fmt.Errorf("invalid bodyBase64: %w", err)
+		}
+		p.raw = raw
+	} else if text, ok := def.Body.(string); ok && def.ContentType != "" {
+		p.raw = []byte(text) // e.g. HTML or XML served as is rather than as a JSON string
+	}
+	if len(def.BodyFiles) > 0 {
+		files, err := loadFileSequence(def.BodyFiles, def.BodyFilesMode, def.ContentType)
+		if err != nil {
+			return p, err
+		}
+		p.files = files
+	}
+	if def.BodyCSV != "" {
+		rows, err := loadCSVBody(def.BodyCSV, def.CSVDelimiter)
+		if err != nil {
+			return p, fmt.Errorf("invalid bodyCsv: %w", err)
+		}
+		p.Body = rows
+	}
+	if def.BodyTemplate != "" {
+		tmpl, err := parseBodyTemplate("bodyTemplate", def.BodyTemplate)
+		if err != nil {
+			return p, fmt.Errorf("invalid bodyTemplate: %w", err)
+		}
+		p.tmpl = tmpl
+	}
+	if def.BodyTemplateFile != "" {
+		if def.BodyTemplate != "" {
+			return p, fmt.Errorf("bodyTemplate and bodyTemplateFile are mutually exclusive")
+		}
+		text, err := os.ReadFile(def.BodyTemplateFile)
+		if err != nil {
+			return p, fmt.Errorf("invalid bodyTemplateFile: %w", err)
+		}
+		tmpl, err := parseBodyTemplate(filepath.Base(def.BodyTemplateFile), string(text))
+		if err != nil {
+			return p, fmt.Errorf("invalid bodyTemplateFile: %w", err)
+		}
+		p.tmpl = tmpl
+	}
+	header, err := parseHeaderTemplates(def.Headers)
+	if err != nil {
+		return p, err
+	}
+	p.header = header
+	if def.BodyScript != "" {
+		if p.tmpl != nil {
+			return p, fmt.Errorf("bodyScript and bodyTemplate are mutually exclusive")
+		}
+		script, err := compileScript(def.BodyScript, time.Duration(def.ScriptTimeoutMs)*time.Millisecond)
+		if err != nil {
+			return p, fmt.Errorf("invalid bodyScript: %w", err)
+		}
+		p.script = script
+	}
+	if len(def.Localized) > 0 {
+		localized, err := prepareLocalized(def)
+		if err != nil {
+			return p, err
+		}
+		p.localized = localized
+	}
+	if def.Download != nil {
+		if err := prepareDownload(&p); err != nil {
+			return p, err
+		}
+	}
+	if def.Transform != nil {
+		if err := prepareTransform(&p); err != nil {
+			return p, err
+		}
+	}
+	if def.StatusFrom != nil {
+		if err := validateStatusFrom(def.StatusFrom); err != nil {
+			return p, err
+		}
+	}
+	if def.NDJSON {
+		if err := validateNDJSON(p.response); err != nil {
+			return p, err
+		}
+	}
+	if def.EchoWithMerge != nil {
+		if _, ok := def.EchoWithMerge.(map[string]any); !ok {
+			return p, fmt.Errorf("invalid echoWithMerge: must be a JSON object")
+		}
+		merge, err := prepareMerge(def.EchoWithMerge)
+		if err != nil {
+			return p, fmt.Errorf("invalid echoWithMerge: %w", err)
+		}
+		p.merge = merge
+	}
+	return p, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *routeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.route.LogRequests != nil && !*h.route.LogRequests {
+		if entry := logEntryFrom(r.Context()); entry != nil {
+			entry.skip = true
+		}
+	}
+	if h.route.LogBody {
+		if entry := logEntryFrom(r.Context()); entry != nil {
+			entry.logBody = true
+			entry.body, _ = readRequestBody(r) // restored for the handler
+		}
+	}
+
+	var err error
+	if h.idempotency != nil {
+		err = h.serveIdempotent(w, r)
+	} else {
+		err = h.serve(w, r)
+	}
+	if err != nil {
+		handleError(w, r, err)
+	}
+}
+
+// serve writes the route's response for the request. Errors are returned to
+// ServeHTTP, which answers with the configured error response.
+func (h *routeHandler) serve(w http.ResponseWriter, r *http.Request) error {
+	if missing := h.missingQuery(r); len(missing) > 0 {
+		msg := "missing required query parameters: " + strings.Join(missing, ", ")
+		return respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	if h.rateLimit != nil {
+		if limited, err := h.rateLimit.limit(w, h.state.now()); limited {
+			return err
+		}
+	}
+
+	if h.route.Sequence != nil {
+		if ok, err := h.checkSequence(w, r); !ok {
+			return err
+		}
+	}
+
+	if h.route.RampDelay != nil {
+		h.waitRamp(r)
+	}
+	if h.route.Latency != nil {
+		sleepRequest(r, h.route.Latency.sample())
+	}
+
+	if h.inFailWindow() {
+		return respondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": "simulated failure (failBetweenMs)"})
+	}
+
+	if h.route.Capture != nil {
+		if err := h.capture(r); err != nil {
+			return fmt.Errorf("capturing request fields: %w", err)
+		}
+	}
+
+	res := h.nextResponse(r)
+	if h.schedule != nil && !h.schedule.open(h.state.now()) {
+		res = h.schedule.closed
+	} else if h.errorBudget != nil && h.errorBudget.fail() {
+		res = h.errorBudget.failure
+	}
+	if res.StatusFrom != nil {
+		status, err := res.StatusFrom.status(r, res.Status)
+		if err != nil {
+			return fmt.Errorf("resolving statusFrom: %w", err)
+		}
+		res.Status = status
+	}
+	if res.localized != nil {
+		i := res.localized.pick(r)
+		lang := res.localized.langs[i]
+		res.Body = res.localized.bodies[i]
+		if res.localized.templated != nil {
+			res.transformBody = res.localized.templated[i]
+		}
+		w.Header().Add("Vary", "Accept-Language")
+		if lang != "" {
+			w.Header().Set("Content-Language", lang)
+		}
+	}
+	rendered, err := renderHeaders(res.header, r, h.state)
+	if err != nil {
+		return fmt.Errorf("rendering headers: %w", err)
+	}
+	replacer := paramReplacer(r)
+	for name, value := range res.Headers {
+		if v, ok := rendered[name]; ok {
+			value = v
+		}
+		if replacer != nil {
+			value = replacer.Replace(value)
+		}
+		w.Header().Set(name, value)
+	}
+	if len(res.Trailers) > 0 {
+		declareTrailers(w, res.Trailers)
+		defer setTrailers(w, res.Trailers)
+		w = &trailerWriter{ResponseWriter: w}
+	}
+	if h.route.Type == routeTypeGRPCWeb {
+		return serveGRPCWeb(w, r, res)
+	}
+	if h.route.Type == routeTypeJSONRPC {
+		return serveJSONRPC(w, r, h.route.RPCMethods)
+	}
+	if h.route.Store != nil {
+		return h.serveStore(w, r, res)
+	}
+	if res.raw != nil {
+		return respondWithBytes(w, res.Status, res.ContentType, res.raw)
+	}
+	if res.files != nil {
+		body, contentType := res.files.next()
+		return respondWithBytes(w, res.Status, contentType, body)
+	}
+	if res.merge != nil {
+		return h.serveEchoWithMerge(w, r, res)
+	}
+	if res.NDJSON {
+		return serveNDJSON(w, r, res)
+	}
+	if res.tmpl != nil {
+		rendered, err := renderTemplate(res.tmpl, r, h.state)
+		if err != nil {
+			return fmt.Errorf("rendering template: %w", err)
+		}
+		return respondWithBytes(w, res.Status, "application/json", rendered)
+	}
+	if res.script != nil {
+		result, err := res.script.run(r, h.state)
+		if err != nil {
+			return err
+		}
+		status := res.Status
+		if result.status != 0 {
+			status = result.status
+		}
+		for name, value := range result.headers {
+			w.Header().Set(name, value)
+		}
+		return respondWithJSON(w, status, result.body)
+	}
+	body, err := h.transform(r, res)
+	if err != nil {
+		return fmt.Errorf("transforming the body: %w", err)
+	}
+	if h.counters != nil {
+		if body, err = h.counters.apply(r, body); err != nil {
+			return fmt.Errorf("applying the counter: %w", err)
+		}
+	}
+	body, err = h.applyOverrides(r, body)
+	if err != nil {
+		return fmt.Errorf("applying overrides: %w", err)
+	}
+	return respondWithJSON(w, res.Status, body)
+}
+
+// validateFailWindow checks a failBetweenMs value is [start, end] with
+// 0 <= start <= end.
+func validateFailWindow(window []int64) error {
+	if len(window) != 2 {
+		return fmt.Errorf("failBetweenMs must be [start, end], got %d values", len(window))
+	}
+	if window[0] < 0 || window[0] > window[1] {
+		return fmt.Errorf("failBetweenMs must satisfy 0 <= start <= end, got %v", window)
+	}
+	return nil
+}
+
+// inFailWindow reports whether the time since startup falls within the
+// route's failBetweenMs window (inclusive).
+func (h *routeHandler) inFailWindow() bool {
+	if len(h.route.FailBetweenMs) != 2 {
+		return false
+	}
+	elapsed := h.state.uptime().Milliseconds()
+	return elapsed >= h.route.FailBetweenMs[0] && elapsed <= h.route.FailBetweenMs[1]
+}
+
+// applyOverrides returns a copy of body with every overridable field whose
+// query param is present replaced by the param's value.
+//
+// Values replacing a non-string field are decoded as JSON when possible so
+// ?count=5 keeps a number a number; everything else is used as a string.
+func (h *routeHandler) applyOverrides(r *http.Request, body any) (any, error) {
+	query := r.URL.Query()
+	var out any
+	for param, path := range h.route.OverridableFields {
+		if !query.Has(param) {
+			continue
+		}
+		if out == nil {
+			var err error
+			if out, err = cloneJSON(body); err != nil {
+				return nil, err
+			}
+		}
+
+		raw := query.Get(param)
+		var value any = raw
+		if current, _ := lookupPath(out, path); current != nil {
+			if _, isString := current.(string); !isString {
+				var decoded any
+				if json.Unmarshal([]byte(raw), &decoded) == nil {
+					value = decoded
+				}
+			}
+		}
+		setPath(out, path, value)
+	}
+	if out == nil {
+		return body, nil
+	}
+	return out, nil
+}
+
+// missingQuery returns the route's required query params that are absent
+// from the request, in config order.
+func (h *routeHandler) missingQuery(r *http.Request) []string {
+	var missing []string
+	query := r.URL.Query()
+	for _, name := range h.route.RequiredQuery {
+		if !query.Has(name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// nextResponse counts the current call and picks the response whose
+// AfterCalls threshold was most recently crossed.
+//
+// With thresholds 0 and 3, calls 1–3 get the first response and every call
+// from the 4th onwards gets the second.
+//
+// In chaos mode one of the chaos responses is picked at random instead;
+// with perClient the client's assigned response is used.
+func (h *routeHandler) nextResponse(r *http.Request) preparedResponse {
+	h.mu.Lock()
+	h.calls++
+	n := h.calls
+	h.mu.Unlock()
+
+	if len(h.chaos) > 0 {
+		return h.chaos[randIntn(len(h.chaos))]
+	}
+	if h.perClient != nil {
+		return h.perClient.pick(r)
+	}
+
+	selected := h.responses[0]
+	for _, res := range h.responses[1:] {
+		if n <= res.AfterCalls {
+			break
+		}
+		selected = res
+	}
+	return selected
+}
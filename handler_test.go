@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// onePixelPNG is a 1x1 PNG image.
+const onePixelPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+
+func TestBinaryResponse(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{
+		"method": "GET", "path": "/avatar.png",
+		"response": {"status": 200, "bodyBase64": "`+onePixelPNG+`", "contentType": "image/png"}
+	}]}`)
+
+	res, body := get(t, srv.URL+"/avatar.png")
+	want, _ := base64.StdEncoding.DecodeString(onePixelPNG)
+	if res.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", res.StatusCode)
+	}
+	if got := res.Header.Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", got)
+	}
+	if got := res.Header.Get("Content-Length"); got != strconv.Itoa(len(want)) {
+		t.Errorf("Content-Length = %q, want %d", got, len(want))
+	}
+	if !bytes.Equal([]byte(body), want) {
+		t.Errorf("body = %x, want %x", body, want)
+	}
+}
+
+func TestBinaryResponseInvalidBase64(t *testing.T) {
+	_, err := BuildRouter(parseInput(t, `{"routes": [{
+		"method": "GET", "path": "/file", "response": {"status": 200, "bodyBase64": "not base64!"}
+	}]}`))
+	if err == nil {
+		t.Fatal("BuildRouter accepted an invalid bodyBase64")
+	}
+}
+
+func TestResponsesAfterCalls(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{
+		"method": "GET", "path": "/quota",
+		"responses": [
+			{"status": 200, "body": "ok"},
+			{"afterCalls": 3, "status": 429, "body": "quota exceeded"}
+		]
+	}]}`)
+
+	want := []int{200, 200, 200, 429, 429}
+	for i, status := range want {
+		if res, _ := get(t, srv.URL+"/quota"); res.StatusCode != status {
+			t.Errorf("call %d: status = %d, want %d", i+1, res.StatusCode, status)
+		}
+	}
+}
+
+func TestRequiredQuery(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{
+		"method": "GET", "path": "/search", "requiredQuery": ["token", "q"],
+		"response": {"status": 200, "body": "ok"}
+	}]}`)
+
+	if res, _ := get(t, srv.URL+"/search?token=abc&q=go"); res.StatusCode != 200 {
+		t.Errorf("all params present: status = %d, want 200", res.StatusCode)
+	}
+	res, body := get(t, srv.URL+"/search?q=go")
+	if res.StatusCode != 400 {
+		t.Errorf("token missing: status = %d, want 400", res.StatusCode)
+	}
+	if !strings.Contains(body, "token") || strings.Contains(body, "q,") {
+		t.Errorf("token missing: body = %s, want it to name only token", body)
+	}
+}
+
+func TestOverridableFields(t *testing.T) {
+	config := `{"routes": [{
+		"method": "GET", "path": "/user",
+		"overridableFields": {"status": "user.status", "count": "user.stats.count"},
+		"response": {"status": 200, "body": {"user": {"name": "Ada", "status": "pending", "stats": {"count": 1}}}}
+	}]}`
+	srv := newTestServer(t, config)
+
+	_, body := get(t, srv.URL+"/user?status=active&count=5")
+	want := `{"user":{"name":"Ada","stats":{"count":5},"status":"active"}}`
+	if got := strings.TrimSpace(body); got != want {
+		t.Errorf("overridden body = %s, want %s", got, want)
+	}
+	_, body = get(t, srv.URL+"/user")
+	want = `{"user":{"name":"Ada","stats":{"count":1},"status":"pending"}}`
+	if got := strings.TrimSpace(body); got != want {
+		t.Errorf("body without overrides = %s, want %s", got, want)
+	}
+
+	invalid := strings.Replace(config, "user.stats.count", "user.missing", 1)
+	if _, err := BuildRouter(parseInput(t, invalid)); err == nil {
+		t.Error("BuildRouter accepted an overridable field missing from the body")
+	}
+}
+
+func TestChaosResponses(t *testing.T) {
+	config := `{"routes": [{
+		"method": "GET", "path": "/flaky",
+		"response": {"status": 200, "body": "normal"},
+		"chaosResponses": [
+			{"status": 200, "body": "ok"},
+			{"status": 500, "body": "boom"},
+			{"status": 503, "body": "unavailable"}
+		]
+	}]}`
+	input := parseInput(t, config)
+	input.Chaos = true
+	srv := serveInput(t, input)
+
+	seen := map[string]bool{}
+	for range 60 {
+		res, body := get(t, srv.URL+"/flaky")
+		seen[strconv.Itoa(res.StatusCode)+" "+body] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("60 chaos requests gave only %v", seen)
+	}
+	if seen[`200 "normal"`] {
+		t.Error("chaos mode served the normal response")
+	}
+
+	srv = newTestServer(t, config)
+	if _, body := get(t, srv.URL+"/flaky"); body != `"normal"` {
+		t.Errorf("without -chaos: body = %s, want \"normal\"", body)
+	}
+}
+
+// newTestRouteHandler decodes a single route definition and prepares its
+// handler with state, for tests that drive the handler directly.
+func newTestRouteHandler(t *testing.T, route string, state *routerState) *routeHandler {
+	t.Helper()
+	var def routesType
+	if err := json.Unmarshal([]byte(route), &def); err != nil {
+		t.Fatalf("parsing route: %v", err)
+	}
+	h, err := newRouteHandler(def, state)
+	if err != nil {
+		t.Fatalf("newRouteHandler: %v", err)
+	}
+	return h
+}
+
+func TestFailBetweenMs(t *testing.T) {
+	state := newRouterState()
+	now := state.started
+	state.now = func() time.Time { return now }
+	h := newTestRouteHandler(t, `{"method": "GET", "path": "/deploy", "failBetweenMs": [100, 200],
+		"response": {"status": 200, "body": "ok"}}`, state)
+
+	for _, tt := range []struct {
+		elapsedMs int
+		status    int
+	}{{0, 200}, {99, 200}, {100, 500}, {150, 500}, {200, 500}, {201, 200}, {5000, 200}} {
+		now = state.started.Add(time.Duration(tt.elapsedMs) * time.Millisecond)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/deploy", nil))
+		if rec.Code != tt.status {
+			t.Errorf("%dms after startup: status = %d, want %d", tt.elapsedMs, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestValidateFailWindow(t *testing.T) {
+	for _, window := range [][]int64{{100}, {200, 100}, {-1, 10}} {
+		if err := validateFailWindow(window); err == nil {
+			t.Errorf("validateFailWindow(%v) accepted an invalid window", window)
+		}
+	}
+}
+
+func TestHeaderPathParams(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "PUT", "path": "/api/orgs/{org}/users/{id}", "response": {"status": 201, "headers": {
+			"Location": "/api/orgs/{org}/users/{id}",
+			"X-Static": "{unknown}"
+		}}}
+	]}`)
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/orgs/acme/users/42", nil)
+	res, _ := do(t, req)
+	if got := res.Header.Get("Location"); got != "/api/orgs/acme/users/42" {
+		t.Errorf("Location = %q, want /api/orgs/acme/users/42", got)
+	}
+	if got := res.Header.Get("X-Static"); got != "{unknown}" {
+		t.Errorf("X-Static = %q, want {unknown} left as is", got)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// harFile is the subset of a HAR 1.2 capture (as exported by browser dev
+// tools) needed to build routes.
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"` // "base64" for binary bodies
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// harHeaderSkip lists response headers not copied into routes: they describe
+// the original transfer rather than the API response.
+var harHeaderSkip = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Content-Type":      true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Date":              true,
+	"Server":            true,
+	"Set-Cookie":        true,
+	"Alt-Svc":           true,
+}
+
+// configFromHAR builds a config from a HAR capture, one route per
+// method+path (the first entry wins; the query is ignored).
+//
+// Entries that are not API calls are skipped: with filter only URLs matching
+// it are kept, and responses of static content (images, fonts, styles,
+// scripts, HTML pages) are always skipped.
+func configFromHAR(data []byte, filter *regexp.Regexp) (scaffoldConfig, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return scaffoldConfig{}, fmt.Errorf("error in Unmarshal of the HAR: %w", err)
+	}
+
+	config := scaffoldConfig{Port: defaultPort, Routes: []scaffoldRoute{}}
+	seen := map[string]bool{}
+	for _, entry := range har.Log.Entries {
+		if filter != nil && !filter.MatchString(entry.Request.URL) {
+			continue
+		}
+		mediaType, _, _ := mime.ParseMediaType(entry.Response.Content.MimeType)
+		if isStaticMediaType(mediaType) {
+			continue
+		}
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			continue
+		}
+		method := normalizeMethod(entry.Request.Method)
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		if key := method + " " + path; seen[key] {
+			continue
+		} else {
+			seen[key] = true
+		}
+
+		body := []byte(entry.Response.Content.Text)
+		if entry.Response.Content.Encoding == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(entry.Response.Content.Text); err != nil {
+				return config, fmt.Errorf("%s %s: invalid base64 body: %w", method, path, err)
+			}
+		}
+		res := scaffoldResponse{Status: entry.Response.Status}
+		res.setBody(body, entry.Response.Content.MimeType)
+		for _, h := range entry.Response.Headers {
+			name := http.CanonicalHeaderKey(h.Name)
+			if harHeaderSkip[name] || strings.HasPrefix(name, ":") {
+				continue
+			}
+			if res.Headers == nil {
+				res.Headers = map[string]string{}
+			}
+			res.Headers[name] = h.Value
+		}
+		config.Routes = append(config.Routes, scaffoldRoute{Method: method, Path: path, Response: res})
+	}
+	return config, nil
+}
+
+// isStaticMediaType reports whether a response of this media type is page
+// content rather than an API response.
+func isStaticMediaType(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "font/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"),
+		mediaType == "text/css",
+		mediaType == "text/html",
+		mediaType == "text/javascript",
+		mediaType == "application/javascript",
+		mediaType == "application/wasm":
+		return true
+	}
+	return false
+}
+
+// importHAR writes the config built from the HAR file at harPath to outPath
+// and returns the number of routes.
+func importHAR(harPath, filter, outPath string) (int, error) {
+	var re *regexp.Regexp
+	if filter != "" {
+		var err error
+		if re, err = regexp.Compile(filter); err != nil {
+			return 0, fmt.Errorf("invalid -har-filter: %w", err)
+		}
+	}
+	data, err := os.ReadFile(harPath)
+	if err != nil {
+		return 0, err
+	}
+	config, err := configFromHAR(data, re)
+	if err != nil {
+		return 0, err
+	}
+	if len(config.Routes) == 0 {
+		return 0, fmt.Errorf("no API entries found in %s", harPath)
+	}
+	return len(config.Routes), writeScaffold(outPath, config)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+const testHAR = `{"log": {"entries": [
+	{"request": {"method": "GET", "url": "https://app.example.com/api/users?page=1"},
+	 "response": {"status": 200, "headers": [{"name": "content-type", "value": "application/json"}, {"name": "x-total", "value": "2"}, {"name": "date", "value": "Mon"}],
+	  "content": {"mimeType": "application/json", "text": "[{\"id\":1}]"}}},
+	{"request": {"method": "GET", "url": "https://app.example.com/api/users?page=2"},
+	 "response": {"status": 200, "content": {"mimeType": "application/json", "text": "[{\"id\":2}]"}}},
+	{"request": {"method": "post", "url": "https://app.example.com/api/users"},
+	 "response": {"status": 201, "headers": [{"name": ":status", "value": "201"}], "content": {"mimeType": "application/json", "text": "{\"id\":3}"}}},
+	{"request": {"method": "GET", "url": "https://app.example.com/logo.png"},
+	 "response": {"status": 200, "content": {"mimeType": "image/png", "text": "iVBORw0K", "encoding": "base64"}}},
+	{"request": {"method": "GET", "url": "https://app.example.com/"},
+	 "response": {"status": 200, "content": {"mimeType": "text/html; charset=utf-8", "text": "<html></html>"}}},
+	{"request": {"method": "GET", "url": "https://cdn.example.com/api/export"},
+	 "response": {"status": 200, "content": {"mimeType": "application/octet-stream", "text": "AAEC", "encoding": "base64"}}}
+]}}`
+
+func TestConfigFromHAR(t *testing.T) {
+	config, err := configFromHAR([]byte(testHAR), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(config.Routes)
+	want := `[` +
+		`{"method":"GET","path":"/api/users","response":{"status":200,"body":[{"id":1}],"headers":{"X-Total":"2"}}},` +
+		`{"method":"POST","path":"/api/users","response":{"status":201,"body":{"id":3}}},` +
+		`{"method":"GET","path":"/api/export","response":{"status":200,"bodyBase64":"AAEC","contentType":"application/octet-stream"}}` +
+		`]`
+	if string(got) != want {
+		t.Errorf("routes =\n%s\nwant\n%s", got, want)
+	}
+
+	config, err = configFromHAR([]byte(testHAR), regexp.MustCompile(`^https://app\.example\.com/api/`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Routes) != 2 {
+		t.Errorf("with filter: %d routes, want 2", len(config.Routes))
+	}
+}
+
+func TestConfigFromHARErrors(t *testing.T) {
+	if _, err := configFromHAR([]byte(`{"log": `), nil); err == nil {
+		t.Error("invalid JSON accepted")
+	}
+	bad := `{"log": {"entries": [{"request": {"method": "GET", "url": "https://x/api"}, "response": {"status": 200, "content": {"mimeType": "application/octet-stream", "text": "%%", "encoding": "base64"}}}]}}`
+	if _, err := configFromHAR([]byte(bad), nil); err == nil {
+		t.Error("invalid base64 body accepted")
+	}
+}
+
+func TestImportHAR(t *testing.T) {
+	dir := t.TempDir()
+	harPath := writeConfig(t, dir, "capture.har", testHAR)
+	out := filepath.Join(dir, "mocker.json")
+
+	n, err := importHAR(harPath, "/api/users", out)
+	if err != nil || n != 2 {
+		t.Fatalf("importHAR = %d, %v; want 2 routes", n, err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, string(data))
+	if res, body := get(t, srv.URL+"/api/users"); body != `[{"id":1}]` || res.Header.Get("X-Total") != "2" {
+		t.Errorf("generated config serves %s %v", body, res.Header)
+	}
+
+	if _, err := importHAR(harPath, "(", out); err == nil {
+		t.Error("invalid -har-filter accepted")
+	}
+	if _, err := importHAR(harPath, "^nothing$", out); err == nil {
+		t.Error("HAR without API entries accepted")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// idempotencyType enables Idempotency-Key handling on a route: the first
+// response for a key is cached and replayed verbatim for later requests with
+// the same key until the TTL expires. Requests arriving while the first one
+// is still being served wait for its response.
+//
+// Example JSON fragment:
+//
+//	"idempotency": { "ttl": "10m" }
+type idempotencyType struct {
+	TTL string `json:"ttl"` // How long a cached response is replayed (Go duration, default: 24h)
+}
+
+// defaultIdempotencyTTL is used when no ttl is configured.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencySkipHeaders are left out of cached responses: they describe the
+// encoding of one particular response and are set again, to match the
+// replaying request, by the compression middleware.
+var idempotencySkipHeaders = map[string]bool{
+	"Content-Encoding": true,
+	"Content-Length":   true,
+	"Vary":             true,
+}
+
+// idempotencyCache stores the first response seen for every key of a route.
+type idempotencyCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]cachedResponse
+	inflight  map[string]*idempotentCall // keys whose first request is being served
+	nextSweep time.Time                  // when expired entries are next removed
+}
+
+// idempotentCall is a first request for a key being served. Requests with
+// the same key wait on done, then replay entry if ok.
+type idempotentCall struct {
+	done  chan struct{}
+	entry cachedResponse
+	ok    bool
+}
+
+// cachedResponse is a captured response and when it stops being replayed.
+type cachedResponse struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// newIdempotencyCache parses the route's idempotency config.
+func newIdempotencyCache(cfg *idempotencyType) (*idempotencyCache, error) {
+	ttl := defaultIdempotencyTTL
+	if cfg.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(cfg.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid idempotency.ttl %q", cfg.TTL)
+		}
+	}
+	return &idempotencyCache{
+		ttl:      ttl,
+		entries:  map[string]cachedResponse{},
+		inflight: map[string]*idempotentCall{},
+	}, nil
+}
+
+// acquire returns the unexpired cached response for key, waiting first for a
+// request with the same key that is still being served. When there is none,
+// it returns a call instead: the caller serves the request and must pass the
+// call to finish.
+func (c *idempotencyCache) acquire(r *http.Request, key string) (cachedResponse, *idempotentCall, error) {
+	for {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		if ok && time.Now().After(entry.expires) {
+			delete(c.entries, key)
+			ok = false
+		}
+		if ok {
+			c.mu.Unlock()
+			return entry, nil, nil
+		}
+		pending := c.inflight[key]
+		if pending == nil {
+			call := &idempotentCall{done: make(chan struct{})}
+			c.inflight[key] = call
+			c.mu.Unlock()
+			return cachedResponse{}, call, nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-pending.done:
+		case <-r.Context().Done():
+			return cachedResponse{}, nil, r.Context().Err()
+		}
+		if pending.ok {
+			return pending.entry, nil, nil
+		}
+		// The first request failed and cached nothing; try again.
+	}
+}
+
+// finish caches entry for key, unless it is nil because the request failed,
+// and wakes up the requests waiting for call. Expired entries are removed
+// at most once per TTL, so the cache holds no more than two TTLs of keys.
+func (c *idempotencyCache) finish(key string, call *idempotentCall, entry *cachedResponse) {
+	c.mu.Lock()
+	now := time.Now()
+	delete(c.inflight, key)
+	if entry != nil {
+		entry.expires = now.Add(c.ttl)
+		c.entries[key] = *entry
+		call.entry, call.ok = *entry, true
+	}
+	if now.After(c.nextSweep) {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// serveIdempotent replays the cached response for the request's
+// Idempotency-Key, or serves the request normally and caches the result.
+// Requests without the header are never cached.
+func (h *routeHandler) serveIdempotent(w http.ResponseWriter, r *http.Request) error {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		return h.serve(w, r)
+	}
+
+	cached, call, err := h.idempotency.acquire(r, key)
+	if err != nil {
+		return err
+	}
+	if call == nil {
+		// Headers set by middleware for this request (e.g. traceparent) win
+		// over the ones captured from the original request.
+		for name, values := range cached.header {
+			if _, set := w.Header()[name]; !set {
+				w.Header()[name] = values
+			}
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(cached.status)
+		_, err := w.Write(cached.body)
+		return err
+	}
+
+	// Failed requests are not cached so a retry with the same key can succeed.
+	var entry *cachedResponse
+	defer func() { h.idempotency.finish(key, call, entry) }()
+
+	// Only the headers the route set are cached; the ones already set by
+	// middleware are set again for every request.
+	before := w.Header().Clone()
+	cw := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+	if err := h.serve(cw, r); err != nil {
+		return err
+	}
+	header := http.Header{}
+	for name, values := range w.Header() {
+		if !idempotencySkipHeaders[name] && !slices.Equal(before[name], values) {
+			header[name] = slices.Clone(values)
+		}
+	}
+	entry = &cachedResponse{status: cw.status, header: header, body: cw.body.Bytes()}
+	return nil
+}
+
+// captureWriter passes a response through while keeping a copy of its
+// status code and body.
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code.
+func (c *captureWriter) WriteHeader(code int) {
+	c.status = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the body bytes.
+func (c *captureWriter) Write(b []byte) (int, error) {
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *captureWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyReplay(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{
+		"method": "POST", "path": "/payments", "idempotency": {"ttl": "1m"},
+		"responses": [
+			{"status": 201, "body": {"id": 1}},
+			{"status": 201, "afterCalls": 1, "body": {"id": 2}},
+			{"status": 201, "afterCalls": 2, "body": {"id": 3}}
+		]
+	}]}`)
+
+	send := func(key string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/payments", strings.NewReader(`{}`))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		return do(t, req)
+	}
+
+	first, firstBody := send("key-1")
+	second, secondBody := send("key-1")
+	if first.StatusCode != 201 || second.StatusCode != 201 {
+		t.Errorf("statuses = %d, %d; want 201, 201", first.StatusCode, second.StatusCode)
+	}
+	if firstBody != `{"id":1}` || secondBody != firstBody {
+		t.Errorf("bodies = %s, %s; want {\"id\":1} twice", firstBody, secondBody)
+	}
+	if second.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("replayed response lacks Idempotent-Replayed: true")
+	}
+
+	if _, body := send("key-2"); body != `{"id":2}` {
+		t.Errorf("new key body = %s, want {\"id\":2}", body)
+	}
+	if _, body := send(""); body != `{"id":3}` {
+		t.Errorf("body without a key = %s, want {\"id\":3}", body)
+	}
+}
+
+func TestIdempotencyInvalidTTL(t *testing.T) {
+	if _, err := newIdempotencyCache(&idempotencyType{TTL: "soon"}); err == nil {
+		t.Error("newIdempotencyCache accepted ttl \"soon\"")
+	}
+}
+
+func TestIdempotencyReplayCompression(t *testing.T) {
+	input := parseInput(t, `{"routes": [{
+		"method": "POST", "path": "/payments", "idempotency": {},
+		"response": {"status": 201, "headers": {"X-Payment": "p-1"}, "body": {"note": "`+strings.Repeat("a", 2000)+`"}}
+	}]}`)
+	input.CompressLevel = 5
+	srv := serveInput(t, input)
+
+	send := func(encoding string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/payments", nil)
+		req.Header.Set("Idempotency-Key", "key-1")
+		req.Header.Set("Accept-Encoding", encoding)
+		res, body := do(t, req)
+		if res.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			plain, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = string(plain)
+		}
+		return res, body
+	}
+
+	first, want := send("gzip")
+	if first.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatal("first response was not compressed")
+	}
+	for _, encoding := range []string{"identity", "gzip"} {
+		res, body := send(encoding)
+		if res.Header.Get("Idempotent-Replayed") != "true" || body != want {
+			t.Errorf("replay with %s: body = %.40s…, want the first response", encoding, body)
+		}
+		if got := res.Header.Values("Content-Encoding"); encoding == "identity" && len(got) != 0 || encoding == "gzip" && len(got) != 1 {
+			t.Errorf("replay with %s: Content-Encoding = %v", encoding, got)
+		}
+		if res.Header.Get("X-Payment") != "p-1" {
+			t.Errorf("replay with %s: X-Payment = %q, want the route's header", encoding, res.Header.Get("X-Payment"))
+		}
+		if got := res.Header.Values("Vary"); len(got) > 1 {
+			t.Errorf("replay with %s: Vary = %v, want it set once", encoding, got)
+		}
+	}
+}
+
+func TestIdempotencyConcurrentFirstRequests(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{
+		"method": "POST", "path": "/payments", "idempotency": {}, "latency": {"ms": 100},
+		"response": {"status": 201, "bodyTemplate": "{\"id\": {{nextId}}}"}
+	}]}`)
+
+	bodies := make([]string, 5)
+	var wg sync.WaitGroup
+	for i := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/payments", nil)
+			req.Header.Set("Idempotency-Key", "same")
+			_, bodies[i] = do(t, req)
+		}()
+	}
+	wg.Wait()
+	for i, body := range bodies {
+		if body != `{"id": 1}` {
+			t.Errorf("request %d: body = %s, want every request to get the first response", i, body)
+		}
+	}
+}
+
+func TestIdempotencyEviction(t *testing.T) {
+	cache, err := newIdempotencyCache(&idempotencyType{TTL: "1ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	for _, key := range []string{"a", "b"} {
+		_, call, err := cache.acquire(r, key)
+		if err != nil || call == nil {
+			t.Fatalf("acquire %s: call = %v, err = %v", key, call, err)
+		}
+		cache.finish(key, call, &cachedResponse{status: 200})
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := cache.entries["a"]; ok {
+		t.Error("expired key a was not evicted")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "time"
+
+// idleMap is a map whose entries are forgotten once they have not been used
+// for ttl, so state keyed by values the clients choose (IPs, headers, path
+// params) cannot grow without bound. Like idempotencyCache, it removes
+// expired entries at most once per ttl, so it holds no more than two TTLs of
+// keys.
+//
+// An idleMap is not safe for concurrent use; its owner guards it.
+type idleMap[K comparable, V any] struct {
+	ttl       time.Duration
+	entries   map[K]idleEntry[V]
+	nextSweep time.Time // when expired entries are next removed
+}
+
+// idleEntry is a value and when it was last used.
+type idleEntry[V any] struct {
+	value    V
+	lastUsed time.Time
+}
+
+// newIdleMap returns an empty idleMap forgetting entries unused for ttl.
+func newIdleMap[K comparable, V any](ttl time.Duration) *idleMap[K, V] {
+	return &idleMap[K, V]{ttl: ttl, entries: map[K]idleEntry[V]{}}
+}
+
+// get returns the value of key, unless it was last used more than ttl before
+// now.
+func (m *idleMap[K, V]) get(key K, now time.Time) (V, bool) {
+	e, ok := m.entries[key]
+	if !ok || now.Sub(e.lastUsed) > m.ttl {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// set stores value for key as used at now, and removes the expired entries
+// when a sweep is due.
+func (m *idleMap[K, V]) set(key K, value V, now time.Time) {
+	m.entries[key] = idleEntry[V]{value: value, lastUsed: now}
+	if now.After(m.nextSweep) {
+		for k, e := range m.entries {
+			if now.Sub(e.lastUsed) > m.ttl {
+				delete(m.entries, k)
+			}
+		}
+		m.nextSweep = now.Add(m.ttl)
+	}
+}
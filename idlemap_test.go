@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleMap(t *testing.T) {
+	m := newIdleMap[string, int](time.Hour)
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	m.set("a", 1, start)
+	m.set("b", 2, start.Add(30*time.Minute))
+	if v, ok := m.get("a", start.Add(time.Hour)); !ok || v != 1 {
+		t.Errorf("a within the TTL: %d, %v; want 1, true", v, ok)
+	}
+	if _, ok := m.get("a", start.Add(61*time.Minute)); ok {
+		t.Error("a is still returned after an hour idle")
+	}
+
+	// The next sweep is due an hour after the first set; it drops a but
+	// keeps b, used since.
+	m.set("c", 3, start.Add(75*time.Minute))
+	if _, ok := m.entries["a"]; ok {
+		t.Error("the sweep kept the expired a")
+	}
+	if v, ok := m.get("b", start.Add(75*time.Minute)); !ok || v != 2 {
+		t.Errorf("b after the sweep: %d, %v; want 2, true", v, ok)
+	}
+}
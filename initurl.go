@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultPort is the port of configs generated by -init-from-url, -har,
+// -record and -replay, and the port -record listens on without -port.
+const defaultPort = "8080"
+
+// scaffoldConfig is the subset of inputType written by -init-from-url, -har
+// and -record, so the starter config only lists the fields that were captured.
+type scaffoldConfig struct {
+	Port   string          `json:"port"`
+	Routes []scaffoldRoute `json:"routes"`
+}
+
+type scaffoldRoute struct {
+	Method   string           `json:"method"`
+	Path     string           `json:"path"`
+	Example  *exampleType     `json:"example,omitempty"`
+	Response scaffoldResponse `json:"response"`
+}
+
+type scaffoldResponse struct {
+	Status      int               `json:"status"`
+	Body        any               `json:"body,omitempty"`
+	BodyBase64  string            `json:"bodyBase64,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// configFromURL performs a GET on rawURL and returns a single-route config
+// serving the captured status and body at the URL's path.
+//
+// JSON bodies are kept as JSON, other text bodies are stored as a string with
+// their contentType, and binary bodies as bodyBase64.
+func configFromURL(client *http.Client, rawURL string) (scaffoldConfig, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return scaffoldConfig{}, fmt.Errorf("invalid URL %q", rawURL)
+	}
+
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return scaffoldConfig{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return scaffoldConfig{}, err
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	res := scaffoldResponse{Status: resp.StatusCode}
+	res.setBody(body, resp.Header.Get("Content-Type"))
+
+	return scaffoldConfig{
+		Port: defaultPort,
+		Routes: []scaffoldRoute{{
+			Method:   http.MethodGet,
+			Path:     path,
+			Response: res,
+		}},
+	}, nil
+}
+
+// setBody stores a captured body: JSON as JSON, other text as a string with
+// its contentType, and binary data as bodyBase64.
+func (res *scaffoldResponse) setBody(body []byte, contentType string) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case len(body) == 0:
+	case json.Valid(body) && (mediaType == "" || strings.Contains(mediaType, "json")):
+		res.Body = json.RawMessage(body)
+	case isTextMediaType(mediaType):
+		res.Body = string(body)
+		res.ContentType = contentType
+	default:
+		res.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+		res.ContentType = contentType
+	}
+}
+
+// isTextMediaType reports whether a body of this media type can be stored as
+// a string in the config.
+func isTextMediaType(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
+
+// initConfigFromURL writes the config scaffolded from rawURL to outPath.
+func initConfigFromURL(rawURL, outPath string) error {
+	config, err := configFromURL(&http.Client{Timeout: 30 * time.Second}, rawURL)
+	if err != nil {
+		return err
+	}
+	return writeScaffold(outPath, config)
+}
+
+// writeScaffold writes a generated config as indented JSON.
+func writeScaffold(outPath string, config scaffoldConfig) error {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false) // keep captured HTML/XML bodies readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(config); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out.Bytes(), 0o644)
+}
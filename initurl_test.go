@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFromURL(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/users":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"id":1,"name":"Ada"}]`))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("<h1>Hi</h1>"))
+		case "/logo":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	for _, tt := range []struct {
+		path string
+		want scaffoldResponse
+	}{
+		{"/api/users", scaffoldResponse{Status: 200, Body: json.RawMessage(`[{"id":1,"name":"Ada"}]`)}},
+		{"/page", scaffoldResponse{Status: 202, Body: "<h1>Hi</h1>", ContentType: "text/html; charset=utf-8"}},
+		{"/logo", scaffoldResponse{Status: 200, BodyBase64: "iVBORw==", ContentType: "image/png"}},
+	} {
+		config, err := configFromURL(upstream.Client(), upstream.URL+tt.path+"?page=2")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if config.Port != defaultPort || len(config.Routes) != 1 {
+			t.Fatalf("%s: config = %+v", tt.path, config)
+		}
+		route := config.Routes[0]
+		if route.Method != http.MethodGet || route.Path != tt.path {
+			t.Errorf("%s: route %s %s", tt.path, route.Method, route.Path)
+		}
+		got, _ := json.Marshal(route.Response)
+		want, _ := json.Marshal(tt.want)
+		if string(got) != string(want) {
+			t.Errorf("%s: response = %s, want %s", tt.path, got, want)
+		}
+	}
+
+	if _, err := configFromURL(upstream.Client(), "not a url"); err == nil {
+		t.Error("invalid URL accepted")
+	}
+}
+
+func TestInitConfigFromURL(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	out := filepath.Join(t.TempDir(), "mocker.json")
+	if err := initConfigFromURL(upstream.URL+"/health", out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The generated config must load and serve the captured response.
+	srv := newTestServer(t, string(data))
+	if _, body := get(t, srv.URL+"/health"); strings.TrimSpace(body) != `{"ok":true}` {
+		t.Errorf("generated config serves %s", body)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// lookupPath walks a decoded JSON value along a dot-separated path such as
+// "user.address.city" or "items.0.sku" (numeric segments index arrays).
+func lookupPath(v any, path string) (any, bool) {
+	cur := v
+	for _, seg := range strings.Split(path, ".") {
+		switch node := cur.(type) {
+		case map[string]any:
+			next, ok := node[seg]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			cur = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// setPath replaces the value at an existing dot-separated path in place.
+//
+// It returns false if the path does not exist.
+func setPath(v any, path string, value any) bool {
+	parent, last := v, path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		var ok bool
+		parent, ok = lookupPath(v, path[:i])
+		if !ok {
+			return false
+		}
+		last = path[i+1:]
+	}
+
+	switch node := parent.(type) {
+	case map[string]any:
+		if _, ok := node[last]; !ok {
+			return false
+		}
+		node[last] = value
+		return true
+	case []any:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(node) {
+			return false
+		}
+		node[i] = value
+		return true
+	}
+	return false
+}
+
+// cloneJSON deep-copies a JSON-compatible value so per-request changes never
+// leak into the configured body.
+func cloneJSON(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
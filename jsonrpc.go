@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// routeTypeJSONRPC is the route type serving JSON-RPC 2.0 calls, all posted to
+// one endpoint and told apart by their "method" field.
+const routeTypeJSONRPC = "jsonrpc"
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+)
+
+// jsonRPCMethod is the configured answer to one JSON-RPC method: a result or
+// an error object.
+//
+// Example JSON fragment:
+//
+//	"rpcMethods": {
+//	  "getUser":    { "result": { "id": 1, "name": "Ada" } },
+//	  "deleteUser": { "error": { "code": -32000, "message": "forbidden" } }
+//	}
+type jsonRPCMethod struct {
+	Result any           `json:"result"` // Result returned in the response envelope
+	Error  *jsonRPCError `json:"error"`  // Error returned instead of a result
+}
+
+// jsonRPCError is a JSON-RPC error object.
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// validateJSONRPC checks a jsonrpc route: calls are POSTs, and every method
+// has either a result or an error with a code.
+func validateJSONRPC(route routesType) error {
+	if route.Method != http.MethodPost {
+		return fmt.Errorf("jsonrpc routes must use POST")
+	}
+	if len(route.RPCMethods) == 0 {
+		return fmt.Errorf("jsonrpc routes need rpcMethods")
+	}
+	for name, method := range route.RPCMethods {
+		if method.Error == nil {
+			continue
+		}
+		if method.Result != nil {
+			return fmt.Errorf("rpcMethods.%s: result and error are mutually exclusive", name)
+		}
+		if method.Error.Code == 0 {
+			return fmt.Errorf("rpcMethods.%s: error.code is required", name)
+		}
+	}
+	return nil
+}
+
+// serveJSONRPC answers a JSON-RPC 2.0 call, or a batch of calls, from the
+// configured methods. Responses carry the id of their call; notifications
+// (calls without an id) get none, and a request made only of notifications
+// is answered with 204.
+func serveJSONRPC(w http.ResponseWriter, r *http.Request, methods map[string]jsonRPCMethod) error {
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	raw = bytes.TrimSpace(raw)
+
+	if len(raw) > 0 && raw[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return respondWithJSON(w, http.StatusOK, jsonRPCErrorReply(nil, jsonRPCParseError, "parse error"))
+		}
+		if len(batch) == 0 {
+			return respondWithJSON(w, http.StatusOK, jsonRPCErrorReply(nil, jsonRPCInvalidRequest, "invalid request: empty batch"))
+		}
+		replies := []any{}
+		for _, call := range batch {
+			if reply, ok := answerJSONRPC(call, methods); ok {
+				replies = append(replies, reply)
+			}
+		}
+		if len(replies) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		return respondWithJSON(w, http.StatusOK, replies)
+	}
+
+	if !json.Valid(raw) {
+		return respondWithJSON(w, http.StatusOK, jsonRPCErrorReply(nil, jsonRPCParseError, "parse error"))
+	}
+	reply, ok := answerJSONRPC(raw, methods)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return respondWithJSON(w, http.StatusOK, reply)
+}
+
+// answerJSONRPC builds the response envelope for one call. It returns false
+// for notifications, which get no response.
+func answerJSONRPC(raw json.RawMessage, methods map[string]jsonRPCMethod) (any, bool) {
+	var call struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		ID      json.RawMessage `json:"id"` // nil when absent, "null" for an explicit null
+	}
+	if err := json.Unmarshal(raw, &call); err != nil || call.JSONRPC != "2.0" || call.Method == "" {
+		return jsonRPCErrorReply(nil, jsonRPCInvalidRequest, "invalid request"), true
+	}
+	if call.ID == nil {
+		return nil, false
+	}
+
+	method, ok := methods[call.Method]
+	switch {
+	case !ok:
+		return jsonRPCErrorReply(call.ID, jsonRPCMethodNotFound, "method not found: "+call.Method), true
+	case method.Error != nil:
+		return map[string]any{"jsonrpc": "2.0", "id": call.ID, "error": method.Error}, true
+	}
+	return map[string]any{"jsonrpc": "2.0", "id": call.ID, "result": method.Result}, true
+}
+
+// jsonRPCErrorReply builds an error envelope; a nil id is sent as null, as
+// the spec requires when the id could not be read.
+func jsonRPCErrorReply(id json.RawMessage, code int, message string) map[string]any {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return map[string]any{"jsonrpc": "2.0", "id": id, "error": jsonRPCError{Code: code, Message: message}}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const jsonRPCConfig = `{"routes": [{"method": "POST", "path": "/rpc", "type": "jsonrpc", "rpcMethods": {
+	"getUser": {"result": {"id": 1, "name": "Ada"}},
+	"deleteUser": {"error": {"code": -32000, "message": "forbidden"}}
+}}]}`
+
+type jsonRPCReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *jsonRPCError   `json:"error"`
+}
+
+func TestJSONRPC(t *testing.T) {
+	srv := newTestServer(t, jsonRPCConfig)
+
+	call := func(body string) jsonRPCReply {
+		t.Helper()
+		res, got := post(t, srv.URL+"/rpc", body)
+		var reply jsonRPCReply
+		if res.StatusCode != 200 || json.Unmarshal([]byte(got), &reply) != nil || reply.JSONRPC != "2.0" {
+			t.Fatalf("%s: got %d %s, want a JSON-RPC 2.0 envelope", body, res.StatusCode, got)
+		}
+		return reply
+	}
+
+	reply := call(`{"jsonrpc": "2.0", "id": 7, "method": "getUser", "params": {"id": 1}}`)
+	if string(reply.ID) != "7" || string(reply.Result) != `{"id":1,"name":"Ada"}` || reply.Error != nil {
+		t.Errorf("getUser: got %+v", reply)
+	}
+
+	reply = call(`{"jsonrpc": "2.0", "id": "abc", "method": "deleteUser"}`)
+	if string(reply.ID) != `"abc"` || reply.Error == nil || reply.Error.Code != -32000 {
+		t.Errorf("deleteUser: got %+v, want the configured error", reply)
+	}
+
+	reply = call(`{"jsonrpc": "2.0", "id": 8, "method": "missing"}`)
+	if string(reply.ID) != "8" || reply.Error == nil || reply.Error.Code != jsonRPCMethodNotFound {
+		t.Errorf("unknown method: got %+v, want -32601", reply)
+	}
+
+	reply = call(`{not json`)
+	if string(reply.ID) != "null" || reply.Error == nil || reply.Error.Code != jsonRPCParseError {
+		t.Errorf("parse error: got %+v, want -32700 with a null id", reply)
+	}
+
+	if res, body := post(t, srv.URL+"/rpc", `{"jsonrpc": "2.0", "method": "getUser"}`); res.StatusCode != 204 {
+		t.Errorf("notification: got %d %s, want 204", res.StatusCode, body)
+	}
+}
+
+func TestJSONRPCBatch(t *testing.T) {
+	srv := newTestServer(t, jsonRPCConfig)
+	res, body := post(t, srv.URL+"/rpc", `[
+		{"jsonrpc": "2.0", "id": 1, "method": "getUser"},
+		{"jsonrpc": "2.0", "method": "getUser"},
+		{"jsonrpc": "2.0", "id": 2, "method": "deleteUser"}
+	]`)
+	var replies []jsonRPCReply
+	if err := json.Unmarshal([]byte(body), &replies); err != nil || res.StatusCode != 200 {
+		t.Fatalf("got %d %s, want a batch of replies", res.StatusCode, body)
+	}
+	if len(replies) != 2 || string(replies[0].ID) != "1" || string(replies[1].ID) != "2" || replies[1].Error == nil {
+		t.Errorf("replies = %s, want answers to calls 1 and 2 only", body)
+	}
+}
+
+func TestValidateJSONRPC(t *testing.T) {
+	for config, want := range map[string]string{
+		`{"routes": [{"method": "GET", "path": "/rpc", "type": "jsonrpc", "rpcMethods": {"a": {"result": 1}}}]}`:                        "POST",
+		`{"routes": [{"method": "POST", "path": "/rpc", "type": "jsonrpc"}]}`:                                                           "rpcMethods",
+		`{"routes": [{"method": "POST", "path": "/rpc", "type": "jsonrpc", "rpcMethods": {"a": {"error": {"message": "x"}}}}]}`:         "error.code",
+		`{"routes": [{"method": "POST", "path": "/rpc", "type": "jsonrpc", "rpcMethods": {"a": {"result": 1, "error": {"code": 1}}}}]}`: "mutually exclusive",
+	} {
+		if _, err := BuildRouter(parseInput(t, config)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want one mentioning %q", config, err, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// latencyType delays a route's responses by a random amount drawn per
+// request from a distribution:
+//
+//   - "fixed" (default): always Ms
+//   - "uniform": anywhere between MinMs and MaxMs
+//   - "normal": Gaussian around Ms with standard deviation StdDevMs,
+//     clamped to zero so it never goes negative
+//
+// Example JSON fragment (mostly 150–250ms, occasionally more):
+//
+//	"latency": { "distribution": "normal", "ms": 200, "stdDevMs": 50 }
+type latencyType struct {
+	Distribution string  `json:"distribution"` // "fixed", "uniform" or "normal"
+	Ms           float64 `json:"ms"`           // Fixed delay, or the mean for "normal"
+	MinMs        float64 `json:"minMs"`        // Lower bound for "uniform"
+	MaxMs        float64 `json:"maxMs"`        // Upper bound for "uniform"
+	StdDevMs     float64 `json:"stdDevMs"`     // Standard deviation for "normal"
+}
+
+// validateLatency checks a latency block.
+func validateLatency(l *latencyType) error {
+	switch l.Distribution {
+	case "", "fixed":
+		if l.Ms < 0 {
+			return fmt.Errorf("latency: ms must not be negative")
+		}
+	case "uniform":
+		if l.MinMs < 0 || l.MaxMs < l.MinMs {
+			return fmt.Errorf("latency: uniform needs 0 <= minMs <= maxMs")
+		}
+	case "normal":
+		if l.Ms < 0 || l.StdDevMs < 0 {
+			return fmt.Errorf("latency: ms and stdDevMs must not be negative")
+		}
+	default:
+		return fmt.Errorf("latency: unknown distribution %q (use fixed, uniform or normal)", l.Distribution)
+	}
+	return nil
+}
+
+// sample draws the delay of one request.
+func (l *latencyType) sample() time.Duration {
+	var ms float64
+	switch l.Distribution {
+	case "uniform":
+		ms = l.MinMs + randFloat64()*(l.MaxMs-l.MinMs)
+	case "normal":
+		ms = max(l.Ms+randNormFloat64()*l.StdDevMs, 0)
+	default:
+		ms = l.Ms
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// sleepRequest sleeps for d, returning early when the client goes away.
+func sleepRequest(r *http.Request, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
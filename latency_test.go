@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLatencyNormal(t *testing.T) {
+	seedRandom(7)
+	t.Cleanup(func() { seedRandom(time.Now().UnixNano()) })
+
+	l := &latencyType{Distribution: "normal", Ms: 200, StdDevMs: 50}
+	const n = 20000
+	var sum, sumSq float64
+	for range n {
+		ms := float64(l.sample()) / float64(time.Millisecond)
+		if ms < 0 {
+			t.Fatalf("negative delay %vms", ms)
+		}
+		sum += ms
+		sumSq += ms * ms
+	}
+	mean := sum / n
+	stdDev := math.Sqrt(sumSq/n - mean*mean)
+	if math.Abs(mean-200) > 2 {
+		t.Errorf("mean delay = %.1fms, want about 200ms", mean)
+	}
+	if math.Abs(stdDev-50) > 2 {
+		t.Errorf("standard deviation = %.1fms, want about 50ms", stdDev)
+	}
+
+	// With a large deviation the delay is clamped at zero instead of going
+	// negative.
+	wide := &latencyType{Distribution: "normal", Ms: 10, StdDevMs: 100}
+	zeros := 0
+	for range 1000 {
+		if d := wide.sample(); d < 0 {
+			t.Fatalf("negative delay %s", d)
+		} else if d == 0 {
+			zeros++
+		}
+	}
+	if zeros == 0 {
+		t.Error("no sample was clamped to zero")
+	}
+}
+
+func TestLatencySample(t *testing.T) {
+	if d := (&latencyType{Ms: 150}).sample(); d != 150*time.Millisecond {
+		t.Errorf("fixed: %s, want 150ms", d)
+	}
+	uniform := &latencyType{Distribution: "uniform", MinMs: 10, MaxMs: 20}
+	for range 100 {
+		if d := uniform.sample(); d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Fatalf("uniform: %s outside 10ms..20ms", d)
+		}
+	}
+}
+
+func TestValidateLatency(t *testing.T) {
+	for _, l := range []latencyType{
+		{Ms: -1},
+		{Distribution: "uniform", MinMs: 20, MaxMs: 10},
+		{Distribution: "normal", Ms: 100, StdDevMs: -5},
+		{Distribution: "poisson", Ms: 100},
+	} {
+		if err := validateLatency(&l); err == nil {
+			t.Errorf("%+v accepted", l)
+		}
+	}
+	if err := validateLatency(&latencyType{Distribution: "normal", Ms: 100, StdDevMs: 20}); err != nil {
+		t.Errorf("valid normal latency: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListenUnix(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, so keep it short.
+	dir, err := os.MkdirTemp("", "mocker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mocker.sock")
+
+	router, err := BuildRouter(parseInput(t, `{"routes": [{"method": "GET", "path": "/api/health", "response": {"status": 200, "body": "ok"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := listenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenUnix(path); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("second listener: err = %v, want the socket reported in use", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runServer(ctx, &http.Server{Handler: router}, ln, "", "", time.Second) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	res, err := client.Get("http://mocker/api/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || string(body) != `"ok"` {
+		t.Errorf("got %d %s, want 200 \"ok\"", res.StatusCode, body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("runServer: %v", err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file still exists after shutdown: %v", err)
+	}
+}
+
+func TestListenUnixNotASocket(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "regular.json", `{}`)
+	if _, err := listenUnix(path); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("err = %v, want a not-a-socket error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"golang.org/x/text/language"
+)
+
+// preparedLocalized holds a response's localized bodies and the matcher
+// picking one of them from Accept-Language.
+type preparedLocalized struct {
+	matcher language.Matcher
+	langs   []string // configured keys; "" at index 0 stands for Body
+	bodies  []any
+
+	templated []any // bodies prepared for transform.templates; nil when not set
+}
+
+// prepareLocalized parses the language tags of def.Localized. Body is the
+// default, served when no language matches.
+func prepareLocalized(def response) (*preparedLocalized, error) {
+	if def.Body == nil {
+		return nil, errors.New("localized needs a body, served when no language matches")
+	}
+	keys := make([]string, 0, len(def.Localized))
+	for key := range def.Localized {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	l := &preparedLocalized{langs: []string{""}, bodies: []any{def.Body}}
+	tags := []language.Tag{language.Und} // the first tag is the matcher's fallback
+	for _, key := range keys {
+		tag, err := language.Parse(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid localized language %q: %w", key, err)
+		}
+		tags = append(tags, tag)
+		l.langs = append(l.langs, key)
+		l.bodies = append(l.bodies, def.Localized[key])
+	}
+	l.matcher = language.NewMatcher(tags)
+	return l, nil
+}
+
+// pick returns the index of the body best matching the request's
+// Accept-Language (e.g. "fr" for "fr-CA, en;q=0.5"), or 0 (Body) when
+// nothing matches.
+func (l *preparedLocalized) pick(r *http.Request) int {
+	accepted, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(accepted) == 0 {
+		return 0
+	}
+	_, index, confidence := l.matcher.Match(accepted...)
+	if confidence == language.No {
+		return 0
+	}
+	return index
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLocalized(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/greeting", "response": {
+		"status": 200,
+		"body": {"message": "Hello"},
+		"localized": {"fr": {"message": "Bonjour"}, "pt-BR": {"message": "Olá"}}
+	}}]}`)
+
+	cases := []struct {
+		accept, body, lang string
+	}{
+		{"fr", `{"message":"Bonjour"}`, "fr"},
+		{"fr-CA, en;q=0.5", `{"message":"Bonjour"}`, "fr"},
+		{"pt-BR", `{"message":"Olá"}`, "pt-BR"},
+		{"de", `{"message":"Hello"}`, ""},
+		{"", `{"message":"Hello"}`, ""},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/greeting", nil)
+		if c.accept != "" {
+			req.Header.Set("Accept-Language", c.accept)
+		}
+		res, body := do(t, req)
+		if strings.TrimSpace(body) != c.body {
+			t.Errorf("Accept-Language %q: body = %s, want %s", c.accept, body, c.body)
+		}
+		if got := res.Header.Get("Content-Language"); got != c.lang {
+			t.Errorf("Accept-Language %q: Content-Language = %q, want %q", c.accept, got, c.lang)
+		}
+	}
+}
+
+func TestLocalizedNeedsBody(t *testing.T) {
+	input := parseInput(t, `{"routes": [{"method": "GET", "path": "/greeting", "response": {
+		"status": 200, "localized": {"fr": "Bonjour"}
+	}}]}`)
+	if _, err := BuildRouter(input); err == nil || !strings.Contains(err.Error(), "body") {
+		t.Errorf("err = %v, want one asking for a default body", err)
+	}
+
+	input = parseInput(t, `{"routes": [{"method": "GET", "path": "/greeting", "response": {
+		"status": 200, "body": "Hello", "localized": {"not a tag!": "x"}
+	}}]}`)
+	if _, err := BuildRouter(input); err == nil || !strings.Contains(err.Error(), "language") {
+		t.Errorf("err = %v, want an invalid language error", err)
+	}
+}
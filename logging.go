@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// logEntry collects per-request logging details. Handlers reach it through
+// the request context to influence the log line written by requestLogger.
+type logEntry struct {
+	skip bool // set by routes with logRequests=false
+
+	logBody bool   // set by routes with logBody=true
+	body    []byte // the request body read for logBody
+}
+
+// logEntryKey is the context key under which the *logEntry is stored.
+type logEntryKey struct{}
+
+// logEntryFrom returns the request's log entry, or nil outside requestLogger.
+func logEntryFrom(ctx context.Context) *logEntry {
+	entry, _ := ctx.Value(logEntryKey{}).(*logEntry)
+	return entry
+}
+
+// logOptions controls what requestLogger writes besides the request line.
+type logOptions struct {
+	bodies bool            // append the request and response bodies
+	redact map[string]bool // lower-cased JSON field names masked in logged bodies
+}
+
+// newLogOptions builds the logging options from the config.
+func newLogOptions(input inputType) logOptions {
+	opts := logOptions{bodies: input.LogBodies, redact: map[string]bool{}}
+	for _, field := range input.Redact {
+		opts.redact[strings.ToLower(field)] = true
+	}
+	return opts
+}
+
+// requestLogger returns a middleware printing one line per request to out
+// once it has been served, using the matched route pattern (e.g.
+// /api/users/{id}) when there is one, the client IP (the forwarded one with
+// -trust-proxy) and the number of response body bytes written (after
+// compression, when enabled).
+//
+// With opts.bodies the request and response bodies are appended, with
+// redacted fields replaced by "***"; what is sent to the client is unchanged.
+// Routes with logBody get their request body appended the same way.
+func requestLogger(out io.Writer, opts logOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entry := &logEntry{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var reqBody []byte
+			var resBody bytes.Buffer
+			if opts.bodies {
+				reqBody, _ = readRequestBody(r)
+				ww.Tee(&resBody)
+			}
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), logEntryKey{}, entry)))
+			if entry.skip {
+				return
+			}
+
+			path := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				path = rctx.RoutePattern()
+			}
+			ip := r.RemoteAddr
+			if addr, ok := clientIP(r); ok {
+				ip = addr.String()
+			}
+			line := fmt.Sprintf("%v %v was called ip=%s bytes=%d", r.Method, path, ip, ww.BytesWritten())
+			if opts.bodies {
+				line += " req=" + opts.formatBody(reqBody) + " res=" + opts.formatBody(resBody.Bytes())
+			} else if entry.logBody {
+				line += " req=" + opts.formatBody(entry.body)
+			}
+			fmt.Fprintln(out, line)
+		})
+	}
+}
+
+// maxLoggedBody caps how many bytes of a body are logged.
+const maxLoggedBody = 1024
+
+// formatBody renders a body for the log: JSON compacted with redacted fields
+// masked, anything else quoted; both are truncated to maxLoggedBody.
+func (opts logOptions) formatBody(body []byte) string {
+	if len(body) == 0 {
+		return "-"
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		if out, err := json.Marshal(opts.redactValue(v)); err == nil {
+			if len(out) > maxLoggedBody {
+				return string(out[:maxLoggedBody]) + "..."
+			}
+			return string(out)
+		}
+	}
+	if len(body) > maxLoggedBody {
+		return strconv.Quote(string(body[:maxLoggedBody])) + "..."
+	}
+	return strconv.Quote(string(body))
+}
+
+// redactValue masks every field whose name is in opts.redact, at any depth.
+func (opts logOptions) redactValue(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		for key, value := range node {
+			if opts.redact[strings.ToLower(key)] {
+				node[key] = "***"
+			} else {
+				node[key] = opts.redactValue(value)
+			}
+		}
+	case []any:
+		for i, value := range node {
+			node[i] = opts.redactValue(value)
+		}
+	}
+	return v
+}
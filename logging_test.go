@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of a server.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// serveLogged serves input with request log lines captured in the returned
+// buffer.
+func serveLogged(t *testing.T, input inputType) (*httptest.Server, *syncBuffer) {
+	t.Helper()
+	logs := &syncBuffer{}
+	input.LogOutput = logs
+	return serveInput(t, input), logs
+}
+
+func TestRouteLogRequests(t *testing.T) {
+	srv, logs := serveLogged(t, parseInput(t, `{"routes": [
+		{"method": "GET", "path": "/poll", "logRequests": false, "response": {"status": 200}},
+		{"method": "GET", "path": "/users/{id}", "response": {"status": 200}}
+	]}`))
+
+	get(t, srv.URL+"/poll")
+	get(t, srv.URL+"/users/7")
+	get(t, srv.URL+"/poll")
+
+	out := logs.String()
+	if strings.Contains(out, "/poll") {
+		t.Errorf("route with logRequests=false was logged:\n%s", out)
+	}
+	if n := strings.Count(out, "GET /users/{id} was called"); n != 1 {
+		t.Errorf("logged route appears %d times, want 1:\n%s", n, out)
+	}
+}
+
+func TestLogOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mocker.log")
+	if err := os.WriteFile(path, []byte("earlier run\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644) // as -log-file opens it
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := parseInput(t, `{"routes": [{"method": "GET", "path": "/users", "response": {"status": 200, "body": "ok"}}]}`)
+	input.LogOutput = f
+	srv := serveInput(t, input)
+	get(t, srv.URL+"/users")
+	get(t, srv.URL+"/missing")
+	f.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != "earlier run" {
+		t.Fatalf("log file:\n%s\nwant the earlier line followed by two request lines", data)
+	}
+	if !strings.HasPrefix(lines[1], "GET /users was called ip=127.0.0.1 ") || !strings.HasPrefix(lines[2], "GET /missing was called") {
+		t.Errorf("request lines = %q", lines[1:])
+	}
+}
+
+func TestLogBytesWritten(t *testing.T) {
+	srv, logs := serveLogged(t, parseInput(t, `{"routes": [
+		{"method": "GET", "path": "/items", "response": {"status": 200, "body": {"items": [1, 2, 3], "name": "large"}}}
+	]}`))
+
+	_, body := get(t, srv.URL+"/items")
+
+	want := fmt.Sprintf("bytes=%d", len(body))
+	if out := logs.String(); !strings.Contains(out, "GET /items was called ip=127.0.0.1 "+want) {
+		t.Errorf("log = %q, want %s", out, want)
+	}
+}
+
+func TestLogBodiesRedact(t *testing.T) {
+	input := parseInput(t, `{"routes": [{"method": "POST", "path": "/login", "response": {"status": 200, "echoWithMerge": {"ok": true}}}]}`)
+	input.LogBodies = true
+	input.Redact = []string{"password", "TOKEN"}
+	srv, logs := serveLogged(t, input)
+
+	_, body := post(t, srv.URL+"/login", `{"user": "ada", "password": "hunter2", "session": {"token": "s3cr3t"}}`)
+	if !strings.Contains(body, `"password":"hunter2"`) || !strings.Contains(body, `"token":"s3cr3t"`) {
+		t.Errorf("response was redacted: %s", body)
+	}
+
+	out := logs.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "s3cr3t") {
+		t.Errorf("secret logged:\n%s", out)
+	}
+	for _, want := range []string{
+		`req={"password":"***","session":{"token":"***"},"user":"ada"}`,
+		`res={"ok":true,"password":"***","session":{"token":"***"},"user":"ada"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log line %q, want it to contain %s", out, want)
+		}
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	opts := logOptions{redact: map[string]bool{"password": true}}
+	long := strings.Repeat("x", maxLoggedBody+10)
+	for body, want := range map[string]string{
+		"":                            "-",
+		`[{"password": "p", "n": 1}]`: `[{"n":1,"password":"***"}]`,
+		"plain text":                  `"plain text"`,
+		long:                          `"` + long[:maxLoggedBody] + `"...`,
+	} {
+		if got := opts.formatBody([]byte(body)); got != want {
+			t.Errorf("formatBody(%.20q) = %.40s, want %.40s", body, got, want)
+		}
+	}
+}
+
+func TestRouteLogBody(t *testing.T) {
+	input := parseInput(t, `{"routes": [
+		{"method": "POST", "path": "/login", "logBody": true, "response": {"status": 200, "echoWithMerge": {"ok": true}}},
+		{"method": "POST", "path": "/orders", "response": {"status": 201}}
+	]}`)
+	input.Redact = []string{"password"}
+	srv, logs := serveLogged(t, input)
+
+	_, body := post(t, srv.URL+"/login", `{"user": "ada", "password": "hunter2"}`)
+	if body != `{"ok":true,"password":"hunter2","user":"ada"}` {
+		t.Errorf("handler did not see the whole body after logging: %s", body)
+	}
+	post(t, srv.URL+"/orders", `{"item": "book"}`)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log:\n%s", logs.String())
+	}
+	if want := `req={"password":"***","user":"ada"}`; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("logBody route: %q, want suffix %s", lines[0], want)
+	}
+	if strings.Contains(lines[1], "req=") || strings.Contains(lines[1], "book") {
+		t.Errorf("route without logBody logged its body: %q", lines[1])
+	}
+}
@@ -4,18 +4,22 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
 	"strings"
-
-	"github.com/go-chi/chi/v5"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // routesType represents a single mocked API route defined in the JSON config.
@@ -37,10 +41,103 @@ import (
 //	    }
 //	  }
 //	}
+//
+// Instead of a single response, a route may list several under "responses";
+// each call picks the entry whose afterCalls threshold was last crossed:
+//
+//	"responses": [
+//	  { "status": 200, "body": { "ok": true } },
+//	  { "status": 429, "afterCalls": 3, "body": { "error": "quota exceeded" } }
+//	]
+//
+// Several routes may share the same method and path when they carry a
+// "match" block; the first one whose constraints hold serves the request and
+// a route without "match" acts as the fallback:
+//
+//	{ "method": "GET", "path": "/api/data",
+//	  "match": { "headers": { "Authorization": "" } },
+//	  "response": { "status": 200, "body": { "data": [1, 2, 3] } } },
+//	{ "method": "GET", "path": "/api/data",
+//	  "response": { "status": 401, "body": { "error": "unauthorized" } } }
 type routesType struct {
-	Method   string   `json:"method"`   // HTTP method to match (GET, POST, PATCH, etc.)
-	Path     string   `json:"path"`     // HTTP path to match (supports static or parameterized paths like /api/users/{id})
-	Response response `json:"response"` // Response definition containing status and body
+	Method        string       `json:"method"`        // HTTP method to match (GET, POST, PATCH, etc.)
+	Path          string       `json:"path"`          // HTTP path to match (supports static or parameterized paths like /api/users/{id})
+	Type          string       `json:"type"`          // Optional protocol: "grpcweb" (gRPC-Web unary calls) or "jsonrpc" (JSON-RPC 2.0); default: plain HTTP/JSON
+	Response      response     `json:"response"`      // Response definition containing status and body
+	Responses     []response   `json:"responses"`     // Optional call-count based responses; overrides Response when set
+	RequiredQuery []string     `json:"requiredQuery"` // Query params that must be present, otherwise 400 is returned
+	Match         *matchType   `json:"match"`         // Optional request constraints; selects among routes sharing method+path
+	Example       *exampleType `json:"example"`       // Optional sample request (e.g. recorded by -record); used by -curl-out
+
+	RPCMethods map[string]jsonRPCMethod `json:"rpcMethods"` // For jsonrpc routes: result or error per JSON-RPC method name
+
+	// OverridableFields maps a query param to a dot-separated path in the
+	// response body (e.g. {"status": "user.status"}); when the param is sent,
+	// its value replaces that field for the current request only.
+	OverridableFields map[string]string `json:"overridableFields"`
+
+	Store       *storeType       `json:"store"`       // Optional in-memory persistence shared with other routes
+	Capture     *captureType     `json:"capture"`     // Optional request fields saved for later templates
+	Idempotency *idempotencyType `json:"idempotency"` // Optional Idempotency-Key replay of the first response
+	LogRequests *bool            `json:"logRequests"` // Print a log line per request (default: true)
+	LogBody     bool             `json:"logBody"`     // Append the request body to the log line (capped, -redact applied)
+	Compress    *bool            `json:"compress"`    // Overrides -compress for this route (e.g. false for already-compressed data)
+	Bandwidth   int              `json:"bandwidth"`   // Pace the response body to this many bytes per second; overrides -bandwidth
+
+	// FailBetweenMs is a [start, end] window, in milliseconds since startup,
+	// during which the route answers 500 (e.g. to simulate a deploy).
+	FailBetweenMs []int64 `json:"failBetweenMs"`
+
+	ErrorBudget *errorBudgetType `json:"errorBudget"` // Optional share of requests answered with an error, spread evenly
+
+	Schedule  *scheduleType  `json:"schedule"`  // Optional opening hours; closedResponse is served outside them
+	RampDelay *rampDelayType `json:"rampDelay"` // Optional delay shrinking (or growing) over the first requests
+	Latency   *latencyType   `json:"latency"`   // Optional random delay per request (fixed, uniform or normal)
+
+	RateLimit *rateLimitType `json:"rateLimit"` // Optional sliding-window request quota (429 when exceeded)
+	Sequence  *sequenceType  `json:"sequence"`  // Optional step of an ordered multi-request flow (409 when out of order)
+	Counter   *counterType   `json:"counter"`   // Optional per-path-param counter returned in the body
+	PerClient *perClientType `json:"perClient"` // Optional sticky response per distinct client (overrides response/responses)
+
+	// ChaosResponses are picked uniformly at random per request when Mocker
+	// runs with -chaos; they are ignored otherwise.
+	ChaosResponses []response `json:"chaosResponses"`
+}
+
+// matchType lists the request constraints a route variant requires.
+//
+// Header values are matched exactly; an empty value only requires the header
+// to be present. Path params are checked against chi's URL params, either
+// exactly or with a regular expression.
+//
+// Example JSON fragment:
+//
+//	"match": {
+//	  "headers": { "Authorization": "", "X-Role": "admin" },
+//	  "pathParams": { "id": { "regex": "^[0-9]+$" } },
+//	  "remoteIP": "10.0.0.0/8"
+//	}
+//
+// The same can be written as a single expression (see whenExpr):
+//
+//	"match": { "when": "header.X-Role == \"admin\" && query.q != \"\"" }
+type matchType struct {
+	Headers    map[string]string         `json:"headers"`    // Header name -> exact value ("" = must be present)
+	PathParams map[string]paramMatchType `json:"pathParams"` // URL param name -> constraint
+	RemoteIP   string                    `json:"remoteIP"`   // Client IP or CIDR range; X-Forwarded-For is used with -trust-proxy
+	When       string                    `json:"when"`       // Boolean expression over the request, see whenExpr
+
+	remoteNet netip.Prefix // parsed RemoteIP
+	when      *whenExpr    // compiled When
+}
+
+// paramMatchType constrains a single path param. When both fields are set,
+// both must hold.
+type paramMatchType struct {
+	Equals *string `json:"equals"` // Exact value
+	Regex  string  `json:"regex"`  // Go regular expression the value must match
+
+	re *regexp.Regexp
 }
 
 // response defines the structure of the HTTP response returned for a mock route.
@@ -53,9 +150,62 @@ type routesType struct {
 //	    "message": "User created successfully"
 //	  }
 //	}
+//
+// Binary payloads (images, PDFs, ...) can be returned with bodyBase64 instead
+// of body; the data is decoded once at startup and written as raw bytes:
+//
+//	"response": {
+//	  "status": 200,
+//	  "contentType": "image/png",
+//	  "bodyBase64": "iVBORw0KGgo..."
+//	}
 type response struct {
-	Status int `json:"status"` // HTTP status code to return (e.g. 200, 201, 404)
-	Body   any `json:"body"`   // JSON body to return — can be object, array, string, number, or boolean
+	Status      int               `json:"status"`      // HTTP status code to return (e.g. 200, 201, 404)
+	Body        any               `json:"body"`        // JSON body to return — can be object, array, string, number, or boolean
+	BodyBase64  string            `json:"bodyBase64"`  // Base64-encoded raw body; takes precedence over Body when set
+	ContentType string            `json:"contentType"` // Content-Type for BodyBase64 (default: application/octet-stream); with a string Body, serves it as is
+	Trailers    map[string]string `json:"trailers"`    // HTTP trailers sent after the body (the response is chunked)
+	Headers     map[string]string `json:"headers"`     // Extra response headers; override defaultHeaders and Content-Type; "{id}" is always replaced by the path param (bodies need transform.params); values may use template functions, e.g. "{{uuid}}"
+
+	BodyFiles     []string `json:"bodyFiles"`     // Files served in turn, one per call; replaces Body
+	BodyFilesMode string   `json:"bodyFilesMode"` // After the last file: "loop" (default) starts over, "stop" repeats it
+
+	BodyCSV      string `json:"bodyCsv"`    // Path of a CSV file served as an array of objects keyed by the header row
+	CSVDelimiter string `json:"delimiter"`  // Field delimiter for BodyCSV (default: ",")
+	AfterCalls   int    `json:"afterCalls"` // Within "responses": used once more than this many calls were made
+
+	// BodyTemplate is a Go text/template rendered per request into the JSON
+	// body; it takes precedence over Body. See templateData for the context.
+	BodyTemplate string `json:"bodyTemplate"`
+
+	// BodyTemplateFile is like BodyTemplate but read from a file, once at
+	// startup (and again on -watch reloads).
+	BodyTemplateFile string `json:"bodyTemplateFile"`
+
+	// BodyScript is a Starlark script run per request to compute the body
+	// and optionally the status and headers; it takes precedence over Body.
+	// See bodyScript for what the script sees.
+	BodyScript      string `json:"bodyScript"`
+	ScriptTimeoutMs int    `json:"scriptTimeoutMs"` // Abort a BodyScript run after this long (default: 100)
+
+	// EchoWithMerge responds with the posted JSON object deep-merged with
+	// this object; string values may use template functions, e.g.
+	// {"id": "{{nextId}}", "createdAt": "{{now}}"}.
+	EchoWithMerge any `json:"echoWithMerge"`
+
+	Download    *downloadType   `json:"download"`    // Optional file download (Content-Disposition: attachment)
+	GRPCStatus  int             `json:"grpcStatus"`  // For grpcweb routes: grpc-status code in the trailer (0 = OK)
+	GRPCMessage string          `json:"grpcMessage"` // For grpcweb routes: grpc-message in the trailer
+	StatusFrom  *statusFromType `json:"statusFrom"`  // Optional status picked from a request body field
+	Transform   *transformType  `json:"transform"`   // Optional body pipeline: params, templates, merge
+
+	// Localized maps language tags (e.g. "fr", "pt-BR") to bodies served
+	// instead of Body to clients preferring that language (Accept-Language);
+	// Body remains the default when none matches.
+	Localized map[string]any `json:"localized"`
+
+	NDJSON      bool `json:"ndjson"`      // Stream an array Body as newline-delimited JSON (application/x-ndjson)
+	LineDelayMs int  `json:"lineDelayMs"` // With NDJSON: pause between lines, in milliseconds
 }
 
 // inputType represents the top-level JSON configuration used by Mocker.
@@ -67,8 +217,58 @@ type response struct {
 //	  "routes": [ ... ]
 //	}
 type inputType struct {
-	Port   string       `json:"port"`   // Port on which the mock server listens
-	Routes []routesType `json:"routes"` // List of routes to configure
+	Port     string       `json:"port"`     // Port on which the mock server listens
+	BasePath string       `json:"basePath"` // Optional prefix prepended to every route path (e.g. /api/v1)
+	Routes   []routesType `json:"routes"`   // List of routes to configure
+
+	// DefaultHeaders are set on every response (e.g. {"X-Mock-Server": "mocker"});
+	// a route's response headers override them.
+	DefaultHeaders map[string]string `json:"defaultHeaders"`
+
+	// Scenarios are named route sets layered over Routes, selected with the
+	// -scenario flag or per request (see scenarioRouter).
+	Scenarios map[string]scenarioType `json:"scenarios"`
+
+	// Static maps a URL prefix to a local directory served as plain files
+	// (e.g. {"/app": "./dist"}); configured routes take precedence.
+	Static map[string]string `json:"static"`
+
+	NotFoundResponse *response `json:"notFoundResponse"` // Served for unknown paths (status defaults to 404)
+	ErrorResponse    *response `json:"errorResponse"`    // Served when a handler panics (status defaults to 500)
+
+	// Settings below come from CLI flags rather than the JSON file.
+	StrictMethods bool `json:"-"` // Answer unsupported methods on known paths with 405 + Allow
+	CompressLevel int  `json:"-"` // gzip/deflate level 1–9 for compressible responses; 0 disables compression
+
+	CORSReflectOrigin bool   `json:"-"` // Echo the request Origin in CORS headers and allow credentials
+	Scenario          string `json:"-"` // Scenario served when a request names none ("" = top-level routes only)
+	Chaos             bool   `json:"-"` // Serve routes' chaosResponses at random
+	TrustProxy        bool   `json:"-"` // Take the client IP from X-Forwarded-For / X-Real-IP
+	ServerHeader      string `json:"-"` // Server header sent with every response, along with an explicit Date
+	NoServerHeaders   bool   `json:"-"` // Suppress the Server and Date headers
+	MaxConcurrent     int    `json:"-"` // Cap on in-flight requests; 0 means unlimited
+	RejectOverflow    bool   `json:"-"` // Answer 503 above MaxConcurrent instead of waiting
+
+	MaxHeaderBytes int `json:"-"` // Requests with larger headers get 431; 0 keeps the net/http default (1 MB)
+
+	Bandwidth int `json:"-"` // Default pace of route response bodies in bytes per second; 0 is unlimited
+
+	LogOutput io.Writer `json:"-"` // Destination of request log lines (default: stdout)
+	Trace     *traceLog `json:"-"` // Optional -trace file every request and response is appended to
+
+	Metrics   *metricsRegistry `json:"-"` // Optional -metrics registry, served on /__metrics
+	LogBodies bool             `json:"-"` // Append request and response bodies to log lines
+	Redact    []string         `json:"-"` // JSON field names masked as "***" in logged bodies
+}
+
+// fullPath returns the path a route is served at once the base path is
+// prepended, collapsing any duplicate slashes at the join.
+func (in inputType) fullPath(p string) string {
+	base := strings.Trim(in.BasePath, "/")
+	if base == "" {
+		return p
+	}
+	return "/" + base + "/" + strings.TrimLeft(p, "/")
 }
 
 // appVersion is the version string printed by the --version flag.
@@ -77,22 +277,83 @@ var appVersion = "v1.0.0"
 // main is the entry point of the Mocker CLI.
 //
 // It handles:
-//   - CLI flags (version, help, path, download, update, uninstall)
+//   - CLI flags (version, help, path, download, update, uninstall, TLS)
 //   - Optional generation of an example config
 //   - Reading and parsing the JSON configuration
 //   - Wiring up HTTP routes using chi
-//   - Starting the HTTP server
+//   - Starting the HTTP (or HTTPS) server
 func main() {
-	var input inputType
-
 	// CLI flags
 	versionFlag := flag.Bool("version", false, "Print version info")
-	path := flag.String("path", "./example.json", "path of the test json file")
+	path := flag.String("path", "", "path of the test json file (default: $MOCKER_CONFIG, ./mocker.json, ~/.config/mocker/config.json)")
+	port := flag.String("port", "", "port to listen on (overrides port in the config)")
+	overridePath := flag.String("override", "", "path of a config deep-merged over -path (routes replaced by method+path, others appended)")
 	helpFlag := flag.Bool("help", false, "Show help message")
 	downloadPath := flag.String("download", "", "Generate an example config file (usage: -download=example.json)")
+	initFromURL := flag.String("init-from-url", "", "GET this URL and write a single-route config replaying its response to -init-out")
+	harPath := flag.String("har", "", "turn the API calls of this HAR capture into a config written to -init-out")
+	harFilter := flag.String("har-filter", "", "with -har, only import entries whose URL matches this regular expression")
+	initOut := flag.String("init-out", "mocker.json", "file written by -init-from-url, -har and -record")
+	record := flag.String("record", "", "proxy every request to this upstream URL and, on shutdown, write the recorded routes to -init-out")
+	recordStrip := flag.String("record-strip", defaultRecordStrip, "comma-separated headers left out of the -record recording (they are still forwarded)")
+	recordBodies := flag.Bool("record-bodies", false, "with -record, also save request bodies in each route's example")
 	update := flag.Bool("update", false, "update to either latest or specific version")
 	version := flag.String("download_verison", "", "mention specific version to be updated to")
 	uninstall := flag.Bool("uninstall", false, "Can be used to uninstall mocker")
+	listVersions := flag.Bool("list-versions", false, "list the released versions available to --update, marking the current one")
+	checkUpdateInterval := flag.Duration("check-update-interval", 0, "periodically check for a newer release while serving and print a notice (e.g. 24h; 0 disables)")
+	tlsCert := flag.String("tls-cert", "", "path of the TLS certificate; serves HTTPS when set together with -tls-key")
+	tlsKey := flag.String("tls-key", "", "path of the TLS private key")
+	tlsPort := flag.String("tls-port", "", "also serve HTTPS on this port (with -tls-cert and -tls-key) while the config port stays plain HTTP")
+	tlsClientCA := flag.String("tls-client-ca", "", "path of a CA bundle; require and verify client certificates signed by it (mutual TLS)")
+	basePath := flag.String("base-path", "", "prefix prepended to every route path (overrides basePath in the config)")
+	listenUnixPath := flag.String("listen-unix", "", "listen on a Unix domain socket at this path instead of the TCP port (removed on shutdown)")
+	reusePort := flag.Bool("reuse-port", false, "set SO_REUSEPORT on the listener so a new instance can bind the same port (Unix only)")
+	strictMethods := flag.Bool("strict-methods", false, "return 405 with an Allow header for undefined methods on a known path")
+	compress := flag.Bool("compress", false, "gzip/deflate compress responses when the client accepts it")
+	compressLevel := flag.Int("compress-level", defaultCompressLevel, "compression level used by -compress, from 1 (fastest) to 9 (smallest)")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile covering the server lifetime to this file")
+	memProfile := flag.String("memprofile", "", "write a heap profile to this file on shutdown")
+	pprofPort := flag.String("pprof-port", "", "serve net/http/pprof on this separate port (disabled when empty)")
+	corsReflect := flag.Bool("cors-reflect-origin", false, "allow CORS from any origin by reflecting the request Origin (credentials allowed)")
+	readTimeout := flag.Duration("read-timeout", 15*time.Second, "maximum duration for reading an entire request (0 disables)")
+	writeTimeout := flag.Duration("write-timeout", 60*time.Second, "maximum duration before timing out writes of a response (0 disables)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 5*time.Second, "on SIGINT/SIGTERM, how long in-flight requests may take before connections are closed forcibly")
+	idleTimeout := flag.Duration("idle-timeout", 120*time.Second, "how long keep-alive connections may stay idle (0 disables)")
+	scenario := flag.String("scenario", "", "name of the scenario from the config to serve by default")
+	configCheck := flag.Bool("config-check", false, "validate the config, report every problem and exit non-zero if there is any")
+	jsonOut := flag.Bool("json", false, "with -config-check, print the findings as JSON")
+	routesJSON := flag.Bool("routes-json", false, "print the effective routes as a JSON array of {method, path, status} and exit")
+	openAPIOut := flag.String("openapi-out", "", "export the config as an OpenAPI 3 spec to this file and exit")
+	curlOut := flag.String("curl-out", "", "write a shell script with one curl command per route to this file and exit")
+	chaos := flag.Bool("chaos", false, "serve a random entry of each route's chaosResponses instead of its normal response")
+	serverHeader := flag.String("server-header", "mocker/"+appVersion, "value of the Server header sent with every response")
+	noServerHeaders := flag.Bool("no-server-headers", false, "omit the Server and Date response headers")
+	maxConcurrent := flag.Int("max-concurrent", 0, "limit the number of requests served at the same time (0 = unlimited)")
+	overflow := flag.String("overflow", "wait", "what -max-concurrent does with excess requests: wait or reject (503)")
+	maxHeaderBytes := flag.Int("max-header-bytes", 0, "reject requests whose headers exceed this many bytes with 431 (0 = net/http default of 1 MB)")
+	maxRequests := flag.Int64("max-requests", 0, "shut down gracefully after serving this many requests, e.g. for a test run (0 = unlimited)")
+	maxConnsPerIP := flag.Int("max-conns-per-ip", 0, "close new connections from a client IP that already has this many open (0 = unlimited)")
+	bandwidth := flag.Int("bandwidth", 0, "pace response bodies to this many bytes per second to simulate a slow link (0 = unlimited)")
+	logFile := flag.String("log-file", "", "append request log lines to this file instead of printing them to stdout")
+	logBodies := flag.Bool("log-bodies", false, "append the request and response bodies to every log line")
+	redact := flag.String("redact", "", "comma-separated JSON field names masked as *** in logged bodies (e.g. password,token)")
+	metrics := flag.Bool("metrics", false, "serve request and response size histograms on GET /__metrics (Prometheus text format)")
+	trace := flag.String("trace", "", "append every request and its response to this file as JSON lines, for -replay")
+	replay := flag.String("replay", "", "serve the responses recorded in this -trace file instead of a config (per method+path, in order)")
+	noBanner := flag.Bool("no-banner", false, "do not print the startup banner (it is only printed to terminals anyway)")
+	mirror := flag.String("mirror", "", "serve the routes of the Mocker running at this URL (e.g. http://master:8080) instead of a local config")
+	mirrorInterval := flag.Duration("mirror-interval", 30*time.Second, "with -mirror, how often to refresh the routes from the master")
+	watch := flag.Bool("watch", false, "reload the routes when the config (or -override) file changes")
+	watchDebounce := flag.Duration("watch-debounce", 200*time.Millisecond, "with -watch, wait this long after the last change before reloading")
+	watchCmd := flag.String("watch-cmd", "", "with -watch, run this shell command after each successful reload (the config path is passed as an argument)")
+	trustProxy := flag.Bool("trust-proxy", false, "take the client IP from X-Forwarded-For / X-Real-IP (only behind a trusted proxy)")
+	seed := flag.Int64("seed", 0, "seed for every random choice (e.g. -chaos, latency) so runs are reproducible; 0 seeds from the current time")
+	readyFile := flag.String("ready-file", "", "write a JSON file with the bound address, PID and routes once the server is listening")
+	bench := flag.Bool("bench", false, "serve a single precomputed route for client throughput testing (ignores -path)")
+	benchPort := flag.String("bench-port", "6969", "port used by -bench")
+	benchPath := flag.String("bench-path", "/", "path served by -bench")
+	benchBody := flag.String("bench-body", `{"ok":true}`, "response body served by -bench")
 	flag.Parse()
 
 	// Handle uninstall flow first so nothing else runs.
@@ -107,6 +368,18 @@ func main() {
 		return
 	}
 
+	// List the releases available to --update and exit.
+	if *listVersions {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		releases, err := listReleases(ctx)
+		if err != nil {
+			log.Fatalf("error in listing the releases, err: %s", err.Error())
+		}
+		printReleases(os.Stdout, releases)
+		return
+	}
+
 	// Show help if requested.
 	if *helpFlag {
 		fmt.Println("Usage:")
@@ -120,6 +393,12 @@ func main() {
 		return
 	}
 
+	// Benchmark mode needs no config file.
+	if *bench {
+		runBench(*benchPort, *benchPath, *benchBody, *shutdownTimeout)
+		return
+	}
+
 	// Generate an example JSON config and exit.
 	if *downloadPath != "" {
 		err := os.WriteFile(*downloadPath, []byte(exampleConfig), 0o644)
@@ -132,48 +411,313 @@ func main() {
 		return // Exit so we don't start the server
 	}
 
-	// Read the JSON config from the provided path.
-	data, err := os.ReadFile(*path)
+	// Scaffold a config from a live endpoint and exit.
+	if *initFromURL != "" {
+		if err := initConfigFromURL(*initFromURL, *initOut); err != nil {
+			log.Fatalf("error in scaffolding config from %s, err: %s", *initFromURL, err.Error())
+		}
+		fmt.Printf("✅ Config for %s written to: %s\n", *initFromURL, *initOut)
+		fmt.Printf("🚀 Run it with: mocker --path=%s\n", *initOut)
+		return
+	}
+
+	// Import routes from a HAR capture and exit.
+	if *harPath != "" {
+		n, err := importHAR(*harPath, *harFilter, *initOut)
+		if err != nil {
+			log.Fatalf("error in importing %s, err: %s", *harPath, err.Error())
+		}
+		fmt.Printf("✅ %d routes from %s written to: %s\n", n, *harPath, *initOut)
+		fmt.Printf("🚀 Run it with: mocker --path=%s\n", *initOut)
+		return
+	}
+
+	// Proxy to an upstream, recording its responses, until interrupted.
+	if *record != "" {
+		rec, err := newRecorder(*record, strings.Split(*recordStrip, ","), *recordBodies)
+		if err != nil {
+			log.Fatalf("error in setting up the recording proxy, err: %s", err.Error())
+		}
+		recordPort := *port
+		if recordPort == "" {
+			recordPort = defaultPort
+		}
+		n, err := runRecorder(rec, ":"+recordPort, *initOut, *maxRequests, *shutdownTimeout)
+		if err != nil {
+			log.Fatalf("error in recording %s, err: %s", *record, err.Error())
+		}
+		fmt.Printf("✅ %d routes recorded from %s written to: %s\n", n, *record, *initOut)
+		fmt.Printf("🚀 Run it with: mocker --path=%s\n", *initOut)
+		return
+	}
+
+	// Find, read and parse the JSON config, applying the override file if
+	// any. A mirror takes its config from the master and a replay from the
+	// trace instead.
+	if *mirror != "" && *replay != "" {
+		log.Fatalf("-mirror and -replay cannot be used together")
+	}
+	var configPath string
+	if *mirror == "" && *replay == "" {
+		var err error
+		if configPath, err = resolveConfigPath(*path); err != nil {
+			log.Fatalf("error in finding the config, err: %s", err.Error())
+		}
+	}
+	if *seed != 0 {
+		seedRandom(*seed)
+	}
+	if *overflow != "wait" && *overflow != "reject" {
+		log.Fatalf("-overflow must be wait or reject, got %q", *overflow)
+	}
+	if *compress && (*compressLevel < 1 || *compressLevel > 9) {
+		log.Fatalf("-compress-level must be between 1 and 9, got %d", *compressLevel)
+	}
+
+	var logOutput io.Writer
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatalf("error in opening the log file, err: %s", err.Error())
+		}
+		defer f.Close()
+		logOutput = f
+	}
+	var traceOutput *traceLog
+	if *trace != "" {
+		f, err := os.OpenFile(*trace, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatalf("error in opening the trace file, err: %s", err.Error())
+		}
+		defer f.Close()
+		traceOutput = newTraceLog(f)
+	}
+
+	// The metrics outlive -watch reloads, like the trace file.
+	var registry *metricsRegistry
+	if *metrics {
+		registry = newMetricsRegistry()
+	}
+
+	// load reads the config and applies the flags that override it; -watch
+	// calls it again on every change.
+	var configFiles atomic.Pointer[[]string] // files read by the last load, for -watch
+	load := func() (inputType, error) {
+		var input inputType
+		var err error
+		switch {
+		case *mirror != "":
+			input, err = loadMirrorConfig(context.Background(), *mirror)
+		case *replay != "":
+			input, err = loadReplayConfig(*replay)
+		default:
+			var files []string
+			if input, files, err = loadConfig(configPath, *overridePath); err == nil {
+				configFiles.Store(&files)
+			}
+		}
+		if err != nil {
+			return input, err
+		}
+		if *port != "" {
+			input.Port = *port
+		}
+		if *basePath != "" {
+			input.BasePath = *basePath
+		}
+		input.StrictMethods = *strictMethods
+		input.CORSReflectOrigin = *corsReflect
+		input.Scenario = *scenario
+		input.Chaos = *chaos
+		input.TrustProxy = *trustProxy
+		input.ServerHeader = *serverHeader
+		input.NoServerHeaders = *noServerHeaders
+		input.MaxConcurrent = *maxConcurrent
+		input.RejectOverflow = *overflow == "reject"
+		input.MaxHeaderBytes = *maxHeaderBytes
+		input.Bandwidth = *bandwidth
+		input.LogOutput = logOutput
+		input.Trace = traceOutput
+		input.Metrics = registry
+		input.LogBodies = *logBodies
+		if *redact != "" {
+			input.Redact = strings.Split(*redact, ",")
+		}
+		if *compress {
+			input.CompressLevel = *compressLevel
+		}
+		return input, nil
+	}
+
+	// Validate the config and exit with the result.
+	if *configCheck {
+		os.Exit(runConfigCheck(os.Stdout, load, *jsonOut))
+	}
+
+	input, err := load()
 	if err != nil {
-		log.Fatalf("error in reading the file, err: %s", err.Error())
+		log.Fatalf("error in loading the config, err: %s", err.Error())
+	}
+
+	// Print the effective route table and exit.
+	if *routesJSON {
+		if err := writeRoutesJSON(os.Stdout, input); err != nil {
+			log.Fatalf("error in writing the routes, err: %s", err.Error())
+		}
+		return
+	}
+
+	// Export the config as OpenAPI and exit.
+	if *openAPIOut != "" {
+		if err := writeOpenAPI(*openAPIOut, input); err != nil {
+			log.Fatalf("error in writing the OpenAPI spec, err: %s", err.Error())
+		}
+		fmt.Printf("✅ OpenAPI spec written to: %s\n", *openAPIOut)
+		return
 	}
 
-	// Parse the JSON into inputType.
-	if err := json.Unmarshal(data, &input); err != nil {
-		log.Fatalf("error in Unmarshal of the JSON, err: %s", err.Error())
+	// Export the config as a curl script and exit.
+	if *curlOut != "" {
+		if err := writeCurlScript(*curlOut, input, "http://localhost:"+input.Port); err != nil {
+			log.Fatalf("error in writing the curl script, err: %s", err.Error())
+		}
+		fmt.Printf("✅ curl script written to: %s\n", *curlOut)
+		return
 	}
 
 	// Set up router and create handlers for each configured route.
-	router := chi.NewRouter()
-	for _, route := range input.Routes {
-		v := route // copy to avoid closure capturing issues
-		router.Method(strings.ToUpper(v.Method), v.Path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			fmt.Printf("%v %v was called\n", r.Method, v.Path)
-			if err := respondWithJSON(w, v.Response.Status, v.Response.Body); err != nil {
-				log.Fatalf("err in responding with json, Error: %s\n", err.Error())
-			}
-		}))
-		fmt.Printf("%v %v set\n", v.Method, v.Path)
+	router, err := BuildRouter(input)
+	if err != nil {
+		log.Fatalf("error in building the router, err: %s", err.Error())
 	}
 
-	// Start the HTTP server.
-	fmt.Println("server is up and running at port: ", input.Port)
-	log.Fatal(http.ListenAndServe(":"+input.Port, router))
-}
+	handler := newSwapHandler(router)
+
+	ctx, stop := signalContext()
+	defer stop()
+	// When one server fails, or -max-requests is reached, all of them are
+	// shut down.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var served http.Handler = handler
+	if *maxRequests > 0 {
+		served = limitRequests(handler, *maxRequests, func() {
+			fmt.Printf("✅ Served %d requests (-max-requests), shutting down\n", *maxRequests)
+			cancel()
+		})
+	}
 
-// respondWithJSON marshals the given payload into JSON and writes it to the
-// HTTP response with the given status code.
-//
-// It returns an error if the JSON marshaling fails.
-func respondWithJSON(w http.ResponseWriter, code int, payload any) error {
-	response, err := json.Marshal(payload)
+	// Configure TLS if requested.
+	timeouts := serverTimeouts{read: *readTimeout, write: *writeTimeout, idle: *idleTimeout}
+	srv := newHTTPServer(served, timeouts, input.MaxHeaderBytes)
+	// With -tls-port the config port stays plain HTTP and a second server
+	// serves HTTPS with the same handler.
+	useTLS := *tlsCert != "" || *tlsKey != ""
+	var tlsSrv *http.Server
+	if useTLS {
+		tlsConfig, err := buildTLSConfig(*tlsClientCA)
+		if err != nil {
+			log.Fatalf("error in setting up TLS, err: %s", err.Error())
+		}
+		if *tlsPort != "" {
+			tlsSrv = newHTTPServer(served, timeouts, input.MaxHeaderBytes)
+			tlsSrv.TLSConfig = tlsConfig
+			useTLS = false
+		} else {
+			srv.TLSConfig = tlsConfig
+		}
+	} else if *tlsClientCA != "" {
+		log.Fatalf("-tls-client-ca requires -tls-cert and -tls-key")
+	} else if *tlsPort != "" {
+		log.Fatalf("-tls-port requires -tls-cert and -tls-key")
+	}
+
+	// Bind the listener ourselves so port "0" picks a free port and the
+	// actual address is known before serving. -listen-unix replaces the TCP
+	// port with a Unix domain socket.
+	var ln net.Listener
+	if *listenUnixPath != "" {
+		if ln, err = listenUnix(*listenUnixPath); err != nil {
+			log.Fatalf("error in listening on %s, err: %s", *listenUnixPath, err.Error())
+		}
+	} else if ln, err = listen(":"+input.Port, *reusePort); err != nil {
+		log.Fatalf("error in listening on port %s, err: %s", input.Port, err.Error())
+	}
+	var tlsLn net.Listener
+	if tlsSrv != nil {
+		if tlsLn, err = listen(":"+*tlsPort, *reusePort); err != nil {
+			log.Fatalf("error in listening on port %s, err: %s", *tlsPort, err.Error())
+		}
+		tlsLn = limitConnsPerIP(tlsLn, *maxConnsPerIP)
+	}
+	ln = limitConnsPerIP(ln, *maxConnsPerIP)
+
+	if *readyFile != "" {
+		if err := writeReadyFile(*readyFile, ln.Addr(), input); err != nil {
+			log.Fatalf("error in writing the ready file, err: %s", err.Error())
+		}
+		defer os.Remove(*readyFile)
+	}
+
+	printBanner(os.Stdout, len(input.Routes), *noBanner, isTerminal(os.Stdout))
+
+	// Start the HTTP(S) server.
+	if *listenUnixPath != "" {
+		fmt.Println("server is up and running at unix socket: ", *listenUnixPath)
+	} else if useTLS {
+		fmt.Println("server is up and running (HTTPS) at port: ", listenPort(ln))
+	} else {
+		fmt.Println("server is up and running at port: ", listenPort(ln))
+	}
+	if tlsLn != nil {
+		fmt.Println("server is up and running (HTTPS) at port: ", listenPort(tlsLn))
+	}
+	if *pprofPort != "" {
+		go servePprof(ctx, *pprofPort)
+	}
+	if *checkUpdateInterval > 0 {
+		go notifyUpdates(ctx, os.Stdout, *checkUpdateInterval)
+	}
+	if *mirror != "" {
+		go pollMirror(ctx, *mirror, *mirrorInterval, func() { reloadRouter(handler, load) })
+		fmt.Printf("🪞 Mirroring routes from %s\n", *mirror)
+	}
+	if *watch && *mirror == "" && *replay == "" {
+		watched := func() []string { return *configFiles.Load() }
+		go watchFiles(ctx, watched, *watchDebounce, func() {
+			if reloadRouter(handler, load) && *watchCmd != "" {
+				runWatchCmd(ctx, *watchCmd, configPath)
+			}
+		})
+		fmt.Printf("👀 Watching %s for changes\n", strings.Join(watched(), ", "))
+	}
+
+	stopProfiling, err := startProfiling(*cpuProfile, *memProfile)
 	if err != nil {
-		return err
+		log.Fatalf("error in starting profiling, err: %s", err.Error())
+	}
+	var wg sync.WaitGroup
+	if tlsSrv != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+			if err := runServer(ctx, tlsSrv, tlsLn, *tlsCert, *tlsKey, *shutdownTimeout); err != nil {
+				log.Printf("HTTPS server error: %s", err.Error())
+			}
+		}()
+	}
+	certFile, keyFile := *tlsCert, *tlsKey
+	if !useTLS {
+		certFile, keyFile = "", "" // with -tls-port the config port serves plain HTTP
+	}
+	if err := runServer(ctx, srv, ln, certFile, keyFile, *shutdownTimeout); err != nil {
+		log.Printf("server error: %s", err.Error())
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	_, err = w.Write(response)
-	return err
+	cancel()
+	wg.Wait()
+	stopProfiling()
 }
 
 // updateMocker downloads and replaces the currently running Mocker binary
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestServer builds the router for a JSON config and serves it on a local
+// httptest server, closed when the test ends.
+func newTestServer(t *testing.T, config string) *httptest.Server {
+	t.Helper()
+	return serveInput(t, parseInput(t, config))
+}
+
+// parseInput decodes a JSON config with request logging discarded.
+// Flag-only settings can be set on the result before calling serveInput.
+func parseInput(t *testing.T, config string) inputType {
+	t.Helper()
+	var input inputType
+	if err := json.Unmarshal([]byte(config), &input); err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+	input.LogOutput = io.Discard
+	return input
+}
+
+// serveInput builds the router for input and serves it on a local httptest
+// server, closed when the test ends.
+func serveInput(t *testing.T, input inputType) *httptest.Server {
+	t.Helper()
+	handler, err := BuildRouter(input)
+	if err != nil {
+		t.Fatalf("BuildRouter: %v", err)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// do sends req and returns the response along with its body.
+func do(t *testing.T, req *http.Request) (*http.Response, string) {
+	t.Helper()
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("reading the body of %s %s: %v", req.Method, req.URL, err)
+	}
+	return res, string(body)
+}
+
+// get sends a GET request to url.
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return do(t, req)
+}
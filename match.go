@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// routeGroup dispatches requests for one method+path among the routes that
+// share it.
+//
+// Variants with a match block are tried in config order; the first whose
+// constraints hold serves the request. Variants without one are fallbacks and
+// are only used when no constrained variant matches.
+type routeGroup struct {
+	variants  []routeVariant
+	fallbacks []routeVariant
+}
+
+// routeVariant is one route of a group along with the handler serving it,
+// which wraps the route in its per-route middleware (e.g. compression).
+type routeVariant struct {
+	match   *matchType
+	handler http.Handler
+}
+
+// add appends a route variant to the group, served through handler.
+func (g *routeGroup) add(h *routeHandler, handler http.Handler) {
+	v := routeVariant{match: h.route.Match, handler: handler}
+	if v.match == nil {
+		g.fallbacks = append(g.fallbacks, v)
+		return
+	}
+	g.variants = append(g.variants, v)
+}
+
+// ServeHTTP implements http.Handler.
+func (g *routeGroup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, v := range g.variants {
+		if v.match.matches(r) {
+			v.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	if len(g.fallbacks) > 0 {
+		g.fallbacks[0].handler.ServeHTTP(w, r)
+		return
+	}
+	_ = respondWithJSON(w, http.StatusNotFound, map[string]string{"error": "no route variant matched the request"})
+}
+
+// matches reports whether the request satisfies every constraint.
+func (m *matchType) matches(r *http.Request) bool {
+	for name, want := range m.Headers {
+		values, ok := r.Header[http.CanonicalHeaderKey(name)]
+		if !ok {
+			return false
+		}
+		if want != "" && !slices.Contains(values, want) {
+			return false
+		}
+	}
+	for name, want := range m.PathParams {
+		if !want.matches(chi.URLParam(r, name)) {
+			return false
+		}
+	}
+	if m.remoteNet.IsValid() {
+		ip, ok := clientIP(r)
+		if !ok || !m.remoteNet.Contains(ip) {
+			return false
+		}
+	}
+	if m.when != nil && !m.when.matches(r) {
+		return false
+	}
+	return true
+}
+
+// clientIP parses the request's RemoteAddr, which holds the forwarded client
+// IP when -trust-proxy is set (see forwardedClientIP).
+func clientIP(r *http.Request) (netip.Addr, bool) {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+// compile prepares the regular expressions and IP range of the match block.
+func (m *matchType) compile() error {
+	if m.When != "" {
+		when, err := compileWhen(m.When)
+		if err != nil {
+			return fmt.Errorf("match.when: %w", err)
+		}
+		m.when = when
+	}
+	if m.RemoteIP != "" {
+		prefix, err := parseIPOrCIDR(m.RemoteIP)
+		if err != nil {
+			return fmt.Errorf("match.remoteIP: %w", err)
+		}
+		m.remoteNet = prefix
+	}
+	for name, p := range m.PathParams {
+		if p.Regex == "" {
+			continue
+		}
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return fmt.Errorf("match.pathParams.%s: %w", name, err)
+		}
+		p.re = re
+		m.PathParams[name] = p
+	}
+	return nil
+}
+
+// parseIPOrCIDR parses "10.0.0.0/8" as a range and "10.1.2.3" as a single
+// address.
+func parseIPOrCIDR(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	ip = ip.Unmap()
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+// matches reports whether a path param value satisfies the constraint.
+func (p paramMatchType) matches(value string) bool {
+	if p.Equals != nil && value != *p.Equals {
+		return false
+	}
+	if p.re != nil && !p.re.MatchString(value) {
+		return false
+	}
+	return true
+}
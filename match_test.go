@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMatchHeaders(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/api/data", "match": {"headers": {"Authorization": ""}},
+		 "response": {"status": 200, "body": {"data": [1, 2, 3]}}},
+		{"method": "GET", "path": "/api/data", "match": {"headers": {"X-Role": "admin"}},
+		 "response": {"status": 200, "body": {"admin": true}}},
+		{"method": "GET", "path": "/api/data", "response": {"status": 401, "body": {"error": "unauthorized"}}}
+	]}`)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{"authorized", map[string]string{"Authorization": "Bearer x"}, 200, `{"data":[1,2,3]}`},
+		{"exact value", map[string]string{"X-Role": "admin"}, 200, `{"admin":true}`},
+		{"wrong value", map[string]string{"X-Role": "guest"}, 401, `{"error":"unauthorized"}`},
+		{"no header", nil, 401, `{"error":"unauthorized"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/data", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			res, body := do(t, req)
+			if res.StatusCode != tt.status || strings.TrimSpace(body) != tt.body {
+				t.Errorf("got %d %s, want %d %s", res.StatusCode, body, tt.status, tt.body)
+			}
+		})
+	}
+}
+
+func TestMatchPathParams(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/users/{id}", "match": {"pathParams": {"id": {"equals": "me"}}},
+		 "response": {"status": 200, "body": "current user"}},
+		{"method": "GET", "path": "/users/{id}", "match": {"pathParams": {"id": {"regex": "^[0-9]+$"}}},
+		 "response": {"status": 200, "body": "user by id"}},
+		{"method": "GET", "path": "/users/{id}", "response": {"status": 400, "body": "invalid id"}}
+	]}`)
+
+	tests := map[string]string{
+		"/users/42":  `"user by id"`,
+		"/users/me":  `"current user"`,
+		"/users/abc": `"invalid id"`,
+		"/users/4a2": `"invalid id"`,
+	}
+	for path, want := range tests {
+		if _, body := get(t, srv.URL+path); body != want {
+			t.Errorf("%s: body = %s, want %s", path, body, want)
+		}
+	}
+
+	_, err := BuildRouter(parseInput(t, `{"routes": [{"method": "GET", "path": "/users/{id}",
+		"match": {"pathParams": {"id": {"regex": "[0-9"}}}, "response": {"status": 200}}]}`))
+	if err == nil || !strings.Contains(err.Error(), "match.pathParams.id") {
+		t.Errorf("invalid regex: err = %v, want one naming match.pathParams.id", err)
+	}
+}
+
+func TestMatchRemoteIP(t *testing.T) {
+	config := `{"routes": [
+		{"method": "GET", "path": "/geo", "match": {"remoteIP": "10.0.0.0/8"}, "response": {"status": 200, "body": "internal"}},
+		{"method": "GET", "path": "/geo", "match": {"remoteIP": "127.0.0.1"}, "response": {"status": 200, "body": "local"}},
+		{"method": "GET", "path": "/geo", "response": {"status": 403, "body": "blocked"}}
+	]}`
+
+	srv := newTestServer(t, config)
+	if _, body := get(t, srv.URL+"/geo"); body != `"local"` {
+		t.Errorf("direct loopback client: body = %s, want \"local\"", body)
+	}
+
+	input := parseInput(t, config)
+	input.TrustProxy = true
+	srv = serveInput(t, input)
+	for ip, want := range map[string]string{
+		"10.1.2.3":    `"internal"`,
+		"203.0.113.9": `"blocked"`,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/geo", nil)
+		req.Header.Set("X-Forwarded-For", ip)
+		if _, body := do(t, req); body != want {
+			t.Errorf("client %s: body = %s, want %s", ip, body, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// metricsPath serves the -metrics histograms in the Prometheus text format.
+const metricsPath = "/__metrics"
+
+// sizeBuckets are the upper bounds, in bytes, of the size histogram buckets.
+var sizeBuckets = []float64{100, 1000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// histogram counts observations per bucket of sizeBuckets.
+type histogram struct {
+	buckets []uint64 // observations <= sizeBuckets[i], not cumulative
+	sum     float64
+	count   uint64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(sizeBuckets))
+	}
+	for i, bound := range sizeBuckets {
+		if v <= bound {
+			h.buckets[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// metricsKey identifies the series of one route: method and route pattern
+// (e.g. /api/users/{id}), or "unmatched" for requests no route served.
+type metricsKey struct {
+	method, route string
+}
+
+// routeMetrics are the histograms kept per route.
+type routeMetrics struct {
+	requestSize, responseSize histogram
+}
+
+// metricsRegistry collects the request metrics of every router, and every
+// reload of it, for the lifetime of the process.
+type metricsRegistry struct {
+	mu     sync.Mutex
+	routes map[metricsKey]*routeMetrics
+}
+
+// newMetricsRegistry returns an empty registry.
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{routes: map[metricsKey]*routeMetrics{}}
+}
+
+// observe records the body sizes of one served request.
+func (m *metricsRegistry) observe(key metricsKey, requestBytes, responseBytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rm, ok := m.routes[key]
+	if !ok {
+		rm = &routeMetrics{}
+		m.routes[key] = rm
+	}
+	rm.requestSize.observe(float64(requestBytes))
+	rm.responseSize.observe(float64(responseBytes))
+}
+
+// labelEscaper escapes label values as the Prometheus text format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// write renders the metrics in the Prometheus text exposition format, series
+// sorted by route and method.
+func (m *metricsRegistry) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]metricsKey, 0, len(m.routes))
+	for key := range m.routes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	families := []struct {
+		name, help string
+		pick       func(*routeMetrics) *histogram
+	}{
+		{"mocker_request_size_bytes", "Size of request bodies in bytes.", func(rm *routeMetrics) *histogram { return &rm.requestSize }},
+		{"mocker_response_size_bytes", "Size of response bodies in bytes, as sent (after compression, when enabled).", func(rm *routeMetrics) *histogram { return &rm.responseSize }},
+	}
+	for _, family := range families {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", family.name, family.help, family.name)
+		for _, key := range keys {
+			h := family.pick(m.routes[key])
+			labels := fmt.Sprintf(`method="%s",route="%s"`, labelEscaper.Replace(key.method), labelEscaper.Replace(key.route))
+			var cumulative uint64
+			for i, bound := range sizeBuckets {
+				cumulative += h.buckets[i]
+				fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", family.name, labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+			}
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", family.name, labels, h.count)
+			fmt.Fprintf(w, "%s_sum{%s} %s\n", family.name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+			fmt.Fprintf(w, "%s_count{%s} %d\n", family.name, labels, h.count)
+		}
+	}
+}
+
+// countingBody counts the bytes read from a request body.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// observeMetrics returns a middleware recording the request and response body
+// sizes of every request in m. The request size is its Content-Length, or the
+// bytes the handler read for chunked requests. The response size counts what
+// reaches the client: compression is applied per route, inside this
+// middleware, so compressed responses are counted compressed.
+func observeMetrics(m *metricsRegistry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := &countingBody{ReadCloser: r.Body}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = body
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			key := metricsKey{method: r.Method, route: "unmatched"}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				key.route = rctx.RoutePattern()
+			}
+			requestBytes := r.ContentLength
+			if requestBytes < 0 {
+				requestBytes = body.n
+			}
+			m.observe(key, requestBytes, int64(ww.BytesWritten()))
+		})
+	}
+}
+
+// withMetrics serves m on metricsPath and everything else through next.
+func withMetrics(next http.Handler, m *metricsRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metricsPath && r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			m.write(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	big := strings.Repeat("a", 2000)
+	input := parseInput(t, `{"routes": [
+		{"method": "POST", "path": "/api/users/{id}", "response": {"status": 200, "body": "`+big+`"}},
+		{"method": "GET", "path": "/plain", "response": {"status": 200, "body": "`+big+`"}}
+	]}`)
+	input.Metrics = newMetricsRegistry()
+	input.CompressLevel = 5
+	srv := serveInput(t, input)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/users/1", strings.NewReader(`{"name":"Ada"}`))
+	req.Header.Set("Accept-Encoding", "gzip")
+	if res, _ := do(t, req); res.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatal("response was not compressed")
+	}
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/plain", nil)
+	req.Header.Set("Accept-Encoding", "identity")
+	do(t, req)
+	get(t, srv.URL+"/missing")
+
+	res, body := get(t, srv.URL+"/__metrics")
+	if res.StatusCode != 200 || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("got %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"# TYPE mocker_request_size_bytes histogram",
+		`mocker_request_size_bytes_sum{method="POST",route="/api/users/{id}"} 14`,
+		`mocker_request_size_bytes_bucket{method="POST",route="/api/users/{id}",le="100"} 1`,
+		// The JSON string is 2002 bytes when sent uncompressed.
+		`mocker_response_size_bytes_sum{method="GET",route="/plain"} 2002`,
+		`mocker_response_size_bytes_bucket{method="GET",route="/plain",le="1000"} 0`,
+		`mocker_response_size_bytes_bucket{method="GET",route="/plain",le="10000"} 1`,
+		`mocker_response_size_bytes_bucket{method="GET",route="/plain",le="+Inf"} 1`,
+		`mocker_response_size_bytes_count{method="GET",route="unmatched"} 1`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+
+	// Compressed responses are counted as sent, i.e. compressed.
+	m := regexp.MustCompile(`mocker_response_size_bytes_sum\{method="POST",route="/api/users/\{id\}"\} (\d+)`).FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("no response size for the compressed route:\n%s", body)
+	}
+	if size, _ := strconv.Atoi(m[1]); size == 0 || size >= 2002 {
+		t.Errorf("compressed response size = %d, want the gzip size, below 2002", size)
+	}
+}
+
+func TestMetricsLabelEscaping(t *testing.T) {
+	m := newMetricsRegistry()
+	m.observe(metricsKey{method: "GET", route: `/a"b\c`}, 0, 1)
+	var out strings.Builder
+	m.write(&out)
+	if want := `mocker_request_size_bytes_count{method="GET",route="/a\"b\\c"} 1`; !strings.Contains(out.String(), want) {
+		t.Errorf("metrics = %s, want %s", out.String(), want)
+	}
+}
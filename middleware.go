@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// traceparentPattern matches a W3C Trace Context traceparent header
+// (version-traceid-parentid-flags), see https://www.w3.org/TR/trace-context/.
+var traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// traceContext echoes the request's traceparent header on the response, or
+// generates a fresh one when the request has none (or an invalid one), so
+// clients can exercise their tracing propagation against the mock.
+//
+// The traceparent is also set on the request so later handlers see it.
+func traceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tp := r.Header.Get("traceparent")
+		if !validTraceparent(tp) {
+			tp = newTraceparent()
+			r.Header.Set("traceparent", tp)
+		}
+		w.Header().Set("traceparent", tp)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validTraceparent reports whether tp is well-formed. All-zero trace and
+// parent IDs are invalid per the spec, as is version ff.
+func validTraceparent(tp string) bool {
+	if !traceparentPattern.MatchString(tp) {
+		return false
+	}
+	return tp[:2] != "ff" &&
+		tp[3:35] != "00000000000000000000000000000000" &&
+		tp[36:52] != "0000000000000000"
+}
+
+// newTraceparent generates a random, sampled version-00 traceparent.
+func newTraceparent() string {
+	var id [24]byte
+	_, _ = rand.Read(id[:])
+	return "00-" + hex.EncodeToString(id[:16]) + "-" + hex.EncodeToString(id[16:]) + "-01"
+}
+
+// defaultHeaders sets the configured headers on every response. Routes can
+// still override them through their own response headers, which are applied
+// later.
+func defaultHeaders(headers map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// serverHeaders sets a Server header and an RFC 1123 Date header on every
+// response. With an empty name both are suppressed instead (net/http would
+// otherwise add Date on its own).
+func serverHeaders(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if name == "" {
+				w.Header()["Date"] = nil
+			} else {
+				w.Header().Set("Server", name)
+				w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitHeaderBytes answers 431 when the request headers take more than max
+// bytes, counted as sent: "Name: value\r\n" per header line.
+//
+// http.Server.MaxHeaderBytes enforces the same limit while reading, but with
+// a few KB of slack; this makes the limit exact.
+func limitHeaderBytes(next http.Handler, max int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size := 0
+		for name, values := range r.Header {
+			for _, value := range values {
+				size += len(name) + len(value) + len(": \r\n")
+			}
+		}
+		if size > max {
+			w.Header().Set("Connection", "close")
+			_ = respondWithJSON(w, http.StatusRequestHeaderFieldsTooLarge,
+				map[string]string{"error": fmt.Sprintf("request headers are %d bytes, more than %d", size, max)})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitConcurrency lets at most max requests through next at a time. Excess
+// requests wait for a free slot (until the client gives up), or get a 503
+// right away when reject is set.
+func limitConcurrency(next http.Handler, max int, reject bool) http.Handler {
+	slots := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reject {
+			select {
+			case slots <- struct{}{}:
+			default:
+				w.Header().Set("Retry-After", "1")
+				_ = respondWithJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "too many concurrent requests"})
+				return
+			}
+		} else {
+			select {
+			case slots <- struct{}{}:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		defer func() { <-slots }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitRequests serves the first max requests through next and calls done
+// once the last of them has been served, e.g. to shut the server down for
+// -max-requests. Requests beyond max that arrive meanwhile get a 503.
+func limitRequests(next http.Handler, max int64, done func()) http.Handler {
+	var started, finished atomic.Int64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if started.Add(1) > max {
+			w.Header().Set("Connection", "close")
+			_ = respondWithJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "server is shutting down (-max-requests reached)"})
+			return
+		}
+		next.ServeHTTP(w, r)
+		if finished.Add(1) == max {
+			done()
+		}
+	})
+}
+
+// forwardedClientIP replaces r.RemoteAddr with the client IP reported by a
+// reverse proxy, for logging and match.remoteIP. Only used with -trust-proxy,
+// since clients can set these headers themselves.
+//
+// The X-Forwarded-For hops (then X-Real-IP) are checked in order and the
+// first valid public address wins; when every hop is internal (private,
+// loopback, link-local) the first valid one is used. Without any valid hop
+// RemoteAddr is left as it is.
+func forwardedClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(header, ",")...)
+		}
+		hops = append(hops, r.Header.Get("X-Real-IP"))
+
+		var first netip.Addr
+		for _, hop := range hops {
+			ip, err := netip.ParseAddr(strings.TrimSpace(hop))
+			if err != nil {
+				continue
+			}
+			ip = ip.Unmap()
+			if !isInternalIP(ip) {
+				first = ip
+				break
+			}
+			if !first.IsValid() {
+				first = ip
+			}
+		}
+		if first.IsValid() {
+			r.RemoteAddr = first.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isInternalIP reports whether ip is a private, loopback, link-local or
+// unspecified address, i.e. most likely a proxy hop rather than the client.
+func isInternalIP(ip netip.Addr) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+}
+
+// corsReflectOrigin allows cross-origin requests from any origin by echoing
+// the request's Origin back, which (unlike "*") is permitted together with
+// credentials. Preflight requests are answered directly with 204.
+func corsReflectOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", r.Header.Get("Access-Control-Request-Method"))
+			if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+				w.Header().Set("Access-Control-Allow-Headers", reqHeaders)
+			}
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// defaultErrorResponse is served by recoverer when the config has no
+// errorResponse.
+var defaultErrorResponse = response{
+	Status: http.StatusInternalServerError,
+	Body:   map[string]string{"error": "internal server error"},
+}
+
+// errorResponseKey is the context key under which recoverer stores the
+// response used by handleError.
+type errorResponseKey struct{}
+
+// recoverer catches panics from handlers further down the chain, logs them
+// with a stack trace and answers with errRes (or defaultErrorResponse), so
+// one broken route never takes the whole server down. Handlers that fail
+// with an ordinary error report it through handleError, which serves the
+// same response.
+func recoverer(errRes *response) func(http.Handler) http.Handler {
+	res := defaultErrorResponse
+	if errRes != nil {
+		res = *errRes
+		if res.Status == 0 {
+			res.Status = http.StatusInternalServerError
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec) // let net/http abort the connection as intended
+				}
+				log.Printf("panic while serving %s %s: %v\n%s", r.Method, r.URL.Path, rec, debug.Stack())
+				respondWithResponse(w, res)
+			}()
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorResponseKey{}, res)))
+		})
+	}
+}
+
+// handleError logs a handler error and answers with the error response set
+// up by recoverer, keeping the server running.
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("error while serving %s %s: %s", r.Method, r.URL.Path, err.Error())
+	res, ok := r.Context().Value(errorResponseKey{}).(response)
+	if !ok {
+		res = defaultErrorResponse
+	}
+	respondWithResponse(w, res)
+}
+
+// notFoundHandler serves the configured notFoundResponse for unknown paths.
+func notFoundHandler(res response) http.HandlerFunc {
+	if res.Status == 0 {
+		res.Status = http.StatusNotFound
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondWithResponse(w, res)
+	}
+}
+
+// respondWithResponse writes a static response definition (status, headers
+// and JSON body), logging write errors instead of failing.
+func respondWithResponse(w http.ResponseWriter, res response) {
+	for name, value := range res.Headers {
+		w.Header().Set(name, value)
+	}
+	if err := respondWithJSON(w, res.Status, res.Body); err != nil {
+		log.Printf("err in responding with json, Error: %s\n", err.Error())
+	}
+}
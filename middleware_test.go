@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTraceContext(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/ping", "response": {"status": 200, "body": "pong"}}]}`)
+
+	res, _ := get(t, srv.URL+"/ping")
+	generated := res.Header.Get("traceparent")
+	if !validTraceparent(generated) {
+		t.Errorf("generated traceparent %q is not valid", generated)
+	}
+	if res, _ := get(t, srv.URL+"/ping"); res.Header.Get("traceparent") == generated {
+		t.Error("two requests without a traceparent got the same one")
+	}
+
+	const sent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ping", nil)
+	req.Header.Set("traceparent", sent)
+	if res, _ := do(t, req); res.Header.Get("traceparent") != sent {
+		t.Errorf("propagated traceparent = %q, want %q", res.Header.Get("traceparent"), sent)
+	}
+
+	req.Header.Set("traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	res, _ = do(t, req)
+	if got := res.Header.Get("traceparent"); !validTraceparent(got) {
+		t.Errorf("invalid incoming traceparent was answered with %q, want a fresh valid one", got)
+	}
+}
+
+func TestDefaultHeaders(t *testing.T) {
+	srv := newTestServer(t, `{
+		"defaultHeaders": {"X-Mock-Server": "mocker", "Access-Control-Expose-Headers": "X-Mock-Server"},
+		"routes": [
+			{"method": "GET", "path": "/plain", "response": {"status": 200}},
+			{"method": "GET", "path": "/custom", "response": {"status": 200, "headers": {"X-Mock-Server": "custom"}}}
+		]}`)
+
+	res, _ := get(t, srv.URL+"/plain")
+	if got := res.Header.Get("X-Mock-Server"); got != "mocker" {
+		t.Errorf("default X-Mock-Server = %q, want mocker", got)
+	}
+	if got := res.Header.Get("Access-Control-Expose-Headers"); got != "X-Mock-Server" {
+		t.Errorf("Access-Control-Expose-Headers = %q, want X-Mock-Server", got)
+	}
+	res, _ = get(t, srv.URL+"/custom")
+	if got := res.Header.Values("X-Mock-Server"); len(got) != 1 || got[0] != "custom" {
+		t.Errorf("overridden X-Mock-Server = %q, want [custom]", got)
+	}
+}
+
+func TestCORSReflectOrigin(t *testing.T) {
+	input := parseInput(t, `{"routes": [{"method": "GET", "path": "/data", "response": {"status": 200}}]}`)
+	input.CORSReflectOrigin = true
+	srv := serveInput(t, input)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/data", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	res, _ := do(t, req)
+	if got := res.Header.Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the request Origin", got)
+	}
+	if got := res.Header.Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+	if got := res.Header.Get("Vary"); got != "Origin" {
+		t.Errorf("Vary = %q, want Origin", got)
+	}
+
+	req, _ = http.NewRequest(http.MethodOptions, srv.URL+"/data", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	req.Header.Set("Access-Control-Request-Headers", "X-Token")
+	res, _ = do(t, req)
+	if res.StatusCode != http.StatusNoContent || res.Header.Get("Access-Control-Allow-Methods") != "PUT" ||
+		res.Header.Get("Access-Control-Allow-Headers") != "X-Token" {
+		t.Errorf("preflight = %d %v, want 204 allowing PUT and X-Token", res.StatusCode, res.Header)
+	}
+
+	if res, _ := get(t, srv.URL+"/data"); res.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Error("request without Origin got Access-Control-Allow-Origin")
+	}
+}
+
+// quietLog discards the standard logger's output for the rest of the test.
+func quietLog(t *testing.T) {
+	t.Helper()
+	old := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(old) })
+}
+
+func TestCustomErrorResponses(t *testing.T) {
+	quietLog(t)
+	input := parseInput(t, `{
+		"notFoundResponse": {"body": {"error": "nothing here", "brand": "acme"}},
+		"errorResponse": {"status": 503, "body": {"error": "acme is down"}},
+		"routes": [{"method": "GET", "path": "/broken", "response": {"status": 200, "bodyTemplate": "{{jsonpath \"[x]\"}}"}}]
+	}`)
+	srv := serveInput(t, input)
+
+	res, body := get(t, srv.URL+"/missing")
+	if res.StatusCode != 404 || body != `{"brand":"acme","error":"nothing here"}` {
+		t.Errorf("unknown path: got %d %s, want the custom 404", res.StatusCode, body)
+	}
+	res, body = get(t, srv.URL+"/broken")
+	if res.StatusCode != 503 || body != `{"error":"acme is down"}` {
+		t.Errorf("failing route: got %d %s, want the custom error response", res.StatusCode, body)
+	}
+
+	panicking := recoverer(input.ErrorResponse)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	panicking.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != 503 || rec.Body.String() != `{"error":"acme is down"}` {
+		t.Errorf("panicking handler: got %d %s, want the custom error response", rec.Code, rec.Body)
+	}
+}
+
+func TestHandlerErrorKeepsServerUp(t *testing.T) {
+	quietLog(t)
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/broken", "response": {"status": 200, "bodyTemplate": "{{seq 1 1000000}}"}},
+		{"method": "GET", "path": "/ok", "response": {"status": 200, "body": "fine"}}
+	]}`)
+
+	for range 2 {
+		res, body := get(t, srv.URL+"/broken")
+		if res.StatusCode != 500 || body != `{"error":"internal server error"}` {
+			t.Errorf("failing route: got %d %s, want 500 with the default error body", res.StatusCode, body)
+		}
+	}
+	if res, body := get(t, srv.URL+"/ok"); res.StatusCode != 200 || body != `"fine"` {
+		t.Errorf("after the failures: got %d %s, want 200 \"fine\"", res.StatusCode, body)
+	}
+}
+
+func TestRecovererKeepsServerUp(t *testing.T) {
+	quietLog(t)
+	calls := 0
+	srv := httptest.NewServer(recoverer(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			var m map[string]int
+			m["boom"]++ // nil map write panics
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer srv.Close()
+
+	if res, _ := get(t, srv.URL); res.StatusCode != 500 {
+		t.Errorf("panicking request: status = %d, want 500", res.StatusCode)
+	}
+	if res, _ := get(t, srv.URL); res.StatusCode != 204 {
+		t.Errorf("next request: status = %d, want 204", res.StatusCode)
+	}
+}
+
+func TestServerHeaders(t *testing.T) {
+	config := `{"routes": [{"method": "GET", "path": "/", "response": {"status": 200}}]}`
+	input := parseInput(t, config)
+	input.ServerHeader = "mocker/" + appVersion
+	srv := serveInput(t, input)
+
+	res, _ := get(t, srv.URL+"/")
+	if got := res.Header.Get("Server"); got != "mocker/"+appVersion {
+		t.Errorf("Server = %q, want mocker/%s", got, appVersion)
+	}
+	date, err := time.Parse(time.RFC1123, res.Header.Get("Date"))
+	if err != nil || !strings.HasSuffix(res.Header.Get("Date"), " GMT") {
+		t.Errorf("Date = %q is not an RFC 1123 GMT date: %v", res.Header.Get("Date"), err)
+	} else if d := time.Since(date); d < -time.Minute || d > time.Minute {
+		t.Errorf("Date = %s, want about now", date)
+	}
+
+	input = parseInput(t, config)
+	input.NoServerHeaders = true
+	srv = serveInput(t, input)
+	res, _ = get(t, srv.URL+"/")
+	if _, ok := res.Header["Server"]; ok {
+		t.Errorf("-no-server-headers: Server = %q", res.Header.Get("Server"))
+	}
+	if _, ok := res.Header["Date"]; ok {
+		t.Errorf("-no-server-headers: Date = %q", res.Header.Get("Date"))
+	}
+}
+
+func TestLimitConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		<-release
+		inFlight.Add(-1)
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		peak.Store(0)
+		srv := httptest.NewServer(limitConcurrency(slow, 2, false))
+		defer srv.Close()
+
+		var wg sync.WaitGroup
+		statuses := make(chan int, 6)
+		for range 6 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := http.Get(srv.URL)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				res.Body.Close()
+				statuses <- res.StatusCode
+			}()
+		}
+		time.Sleep(100 * time.Millisecond)
+		if n := inFlight.Load(); n != 2 {
+			t.Errorf("%d requests in flight, want 2", n)
+		}
+		for range 6 {
+			release <- struct{}{}
+		}
+		wg.Wait()
+		close(statuses)
+		for status := range statuses {
+			if status != 200 {
+				t.Errorf("queued request status = %d, want 200", status)
+			}
+		}
+		if p := peak.Load(); p != 2 {
+			t.Errorf("peak concurrency = %d, want 2", p)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		srv := httptest.NewServer(limitConcurrency(slow, 2, true))
+		defer srv.Close()
+
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if res, err := http.Get(srv.URL); err == nil {
+					res.Body.Close()
+				}
+			}()
+		}
+		for deadline := time.Now().Add(2 * time.Second); inFlight.Load() < 2 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		res, _ := get(t, srv.URL)
+		if res.StatusCode != http.StatusServiceUnavailable || res.Header.Get("Retry-After") == "" {
+			t.Errorf("request over the limit: status = %d, Retry-After = %q; want 503 with Retry-After", res.StatusCode, res.Header.Get("Retry-After"))
+		}
+		release <- struct{}{}
+		release <- struct{}{}
+		wg.Wait()
+	})
+}
+
+func TestTrustProxy(t *testing.T) {
+	input := parseInput(t, `{"routes": [{"method": "GET", "path": "/users", "response": {"status": 200}}]}`)
+	input.TrustProxy = true
+	srv, logs := serveLogged(t, input)
+
+	for _, tt := range []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"first public hop", map[string]string{"X-Forwarded-For": "10.0.0.5, 198.51.100.7, 203.0.113.1"}, "ip=198.51.100.7 "},
+		{"all internal", map[string]string{"X-Forwarded-For": "10.0.0.5, 192.168.1.1"}, "ip=10.0.0.5 "},
+		{"x-real-ip", map[string]string{"X-Real-IP": "203.0.113.4"}, "ip=203.0.113.4 "},
+		{"invalid hops", map[string]string{"X-Forwarded-For": "unknown, not-an-ip"}, "ip=127.0.0.1 "},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/users", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		do(t, req)
+		lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		if last := lines[len(lines)-1]; !strings.Contains(last, tt.want) {
+			t.Errorf("%s: log line %q, want %q", tt.name, last, tt.want)
+		}
+	}
+
+	// Without -trust-proxy the headers are ignored.
+	srv, logs = serveLogged(t, parseInput(t, `{"routes": [{"method": "GET", "path": "/users", "response": {"status": 200}}]}`))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/users", nil)
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	do(t, req)
+	if out := logs.String(); !strings.Contains(out, "ip=127.0.0.1 ") {
+		t.Errorf("untrusted X-Forwarded-For was used: %q", out)
+	}
+}
+
+func TestMaxHeaderBytes(t *testing.T) {
+	input := parseInput(t, `{"routes": [{"method": "GET", "path": "/users", "response": {"status": 200, "body": "ok"}}]}`)
+	input.MaxHeaderBytes = 1024
+	handler, err := BuildRouter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config = newHTTPServer(handler, serverTimeouts{}, input.MaxHeaderBytes)
+	srv.Start()
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name   string
+		size   int
+		status int
+	}{
+		{"small headers", 100, http.StatusOK},
+		{"just over the limit", 1100, http.StatusRequestHeaderFieldsTooLarge},    // rejected by limitHeaderBytes
+		{"far over the limit", 64 << 10, http.StatusRequestHeaderFieldsTooLarge}, // rejected by net/http
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/users", nil)
+		req.Header.Set("X-Padding", strings.Repeat("a", tt.size))
+		res, body := do(t, req)
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, res.StatusCode, tt.status, body)
+		}
+	}
+}
+
+func TestLimitRequests(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := limitRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}), 2, cancel)
+	done := make(chan error, 1)
+	go func() { done <- runServer(ctx, &http.Server{Handler: handler}, ln, "", "", time.Second) }()
+
+	url := "http://" + ln.Addr().String() + "/"
+	for i := 1; i <= 2; i++ {
+		if res, body := get(t, url); res.StatusCode != 200 || body != "ok" {
+			t.Fatalf("request %d: got %d %s, want 200 ok", i, res.StatusCode, body)
+		}
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runServer: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down after -max-requests=2")
+	}
+	if res, err := http.Get(url); err == nil {
+		res.Body.Close()
+		t.Error("third request succeeded, want the server to be gone")
+	}
+}
+
+func TestLimitRequestsRejectsExtra(t *testing.T) {
+	var calls int
+	handler := limitRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), 1, func() { calls++ })
+	for i, want := range []int{200, 503, 503} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("done called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// routesDumpPath serves the effective config of a running Mocker, so another
+// instance can reproduce its routes with -mirror.
+const routesDumpPath = "/__routes-json"
+
+// withRoutesDump serves the config dump on routesDumpPath and everything else
+// through next.
+//
+// The dump is the config as loaded (after -override and -base-path), minus
+// flag-only settings. Routes reading local files (bodyCsv, bodyFiles,
+// bodyTemplateFile, download.file) need the same files on the mirror.
+func withRoutesDump(next http.Handler, input inputType) (http.Handler, error) {
+	dump, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == routesDumpPath && r.Method == http.MethodGet {
+			_ = respondWithBytes(w, http.StatusOK, "application/json", dump)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), nil
+}
+
+// fetchRoutesDump downloads the config dump of the Mocker at baseURL.
+func fetchRoutesDump(ctx context.Context, baseURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+routesDumpPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP %d from %s", resp.StatusCode, req.URL)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// loadMirrorConfig fetches and decodes the config of the Mocker at baseURL.
+// The master's port is not taken over: the mirror listens on -port, or the
+// default port.
+func loadMirrorConfig(ctx context.Context, baseURL string) (inputType, error) {
+	var input inputType
+	dump, err := fetchRoutesDump(ctx, baseURL)
+	if err != nil {
+		return input, err
+	}
+	if err := json.Unmarshal(dump, &input); err != nil {
+		return input, fmt.Errorf("error in Unmarshal of the routes from %s: %w", baseURL, err)
+	}
+	input.Port = defaultPort
+	return input, nil
+}
+
+// pollMirror fetches the master's dump every interval until ctx is cancelled
+// and calls onChange whenever it differs from the previous one. Failed
+// fetches are reported and retried on the next tick.
+func pollMirror(ctx context.Context, baseURL string, interval time.Duration, onChange func()) {
+	last, _ := fetchRoutesDump(ctx, baseURL)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		dump, err := fetchRoutesDump(ctx, baseURL)
+		if err != nil {
+			fmt.Printf("❌ Mirror refresh from %s failed: %v\n", baseURL, err)
+			continue
+		}
+		if !bytes.Equal(dump, last) {
+			last = dump
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMirrorRoutes(t *testing.T) {
+	master := newTestServer(t, `{"port": "9999", "routes": [
+		{"method": "GET", "path": "/users/{id}", "response": {"status": 200, "body": {"name": "Ada"}}},
+		{"method": "POST", "path": "/users", "response": {"status": 201, "body": "created"}}
+	]}`)
+
+	input, err := loadMirrorConfig(context.Background(), master.URL+"/")
+	if err != nil {
+		t.Fatalf("loadMirrorConfig: %v", err)
+	}
+	if input.Port != defaultPort {
+		t.Errorf("mirror port = %q, want %q rather than the master's", input.Port, defaultPort)
+	}
+	input.LogOutput = io.Discard
+	mirror := serveInput(t, input)
+
+	if res, body := get(t, mirror.URL+"/users/7"); res.StatusCode != http.StatusOK || body != `{"name":"Ada"}` {
+		t.Errorf("GET /users/7 on mirror: %d %s", res.StatusCode, body)
+	}
+	if res, body := post(t, mirror.URL+"/users", `{}`); res.StatusCode != http.StatusCreated || body != `"created"` {
+		t.Errorf("POST /users on mirror: %d %s", res.StatusCode, body)
+	}
+}
+
+func TestLoadMirrorConfigErrors(t *testing.T) {
+	for name, handler := range map[string]http.HandlerFunc{
+		"not found":    http.NotFound,
+		"invalid JSON": func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("{")) },
+	} {
+		stub := httptest.NewServer(handler)
+		if _, err := loadMirrorConfig(context.Background(), stub.URL); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+		stub.Close()
+	}
+}
+
+func TestPollMirror(t *testing.T) {
+	var mu sync.Mutex
+	dump := `{"routes": []}`
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != routesDumpPath {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write([]byte(dump))
+	}))
+	defer stub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 10)
+	go pollMirror(ctx, stub.URL, 10*time.Millisecond, func() { changes <- struct{}{} })
+
+	time.Sleep(50 * time.Millisecond)
+	if len(changes) != 0 {
+		t.Fatalf("%d reloads without a change on the master", len(changes))
+	}
+	mu.Lock()
+	dump = `{"routes": [{"method": "GET", "path": "/new", "response": {"status": 200}}]}`
+	mu.Unlock()
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reload after the master's routes changed")
+	}
+}
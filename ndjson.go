@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// serveNDJSON streams an array body as newline-delimited JSON, one element
+// per line, flushing after every line and waiting LineDelayMs between lines.
+// A client that disconnects ends the stream early.
+func serveNDJSON(w http.ResponseWriter, r *http.Request, res preparedResponse) error {
+	items, _ := res.Body.([]any)
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(res.Status)
+
+	rc := http.NewResponseController(w)
+	delay := time.Duration(res.LineDelayMs) * time.Millisecond
+	for i, item := range items {
+		if i > 0 && delay > 0 {
+			select {
+			case <-r.Context().Done():
+				return nil
+			case <-time.After(delay):
+			}
+		}
+		line, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		_ = rc.Flush() // not every writer can flush; the line is still sent
+	}
+	return nil
+}
+
+// validateNDJSON checks an NDJSON response has an array body (bodyCsv is
+// already loaded into Body at this point).
+func validateNDJSON(def response) error {
+	if _, ok := def.Body.([]any); !ok {
+		return fmt.Errorf("ndjson needs an array body")
+	}
+	if def.LineDelayMs < 0 {
+		return fmt.Errorf("lineDelayMs must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNDJSONStream(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/export", "response": {"status": 200,
+		"ndjson": true, "lineDelayMs": 100, "body": [{"id": 1}, {"id": 2}, {"id": 3}]}}]}`)
+
+	start := time.Now()
+	res, err := http.Get(srv.URL + "/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if got := res.Header.Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", got)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	var ids []float64
+	for scanner.Scan() {
+		if len(ids) == 0 && time.Since(start) > 150*time.Millisecond {
+			t.Errorf("first line arrived after %s; lines are not flushed as they are written", time.Since(start))
+		}
+		var item map[string]float64
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		ids = append(ids, item["id"])
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("ids = %v, want [1 2 3]", ids)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("stream took %s, want at least the two 100ms line delays", elapsed)
+	}
+}
+
+func TestNDJSONNeedsArray(t *testing.T) {
+	_, err := BuildRouter(parseInput(t, `{"routes": [{"method": "GET", "path": "/x", "response": {"ndjson": true, "body": {"id": 1}}}]}`))
+	if err == nil {
+		t.Error("BuildRouter accepted ndjson with an object body")
+	}
+}
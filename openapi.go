@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pathParamPattern finds {name} segments in a route path, capturing the name
+// and chi's optional regex suffix (":[0-9]+" in {id:[0-9]+}) separately.
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// writeOpenAPI exports the config as a minimal OpenAPI 3 document with a
+// path item per route, one operation per method and every configured
+// response as an example.
+func writeOpenAPI(path string, input inputType) error {
+	paths := map[string]map[string]any{}
+	for _, route := range input.Routes {
+		full := input.fullPath(route.Path)
+		specPath := pathParamPattern.ReplaceAllString(full, "{$1}")
+		if paths[specPath] == nil {
+			paths[specPath] = map[string]any{}
+		}
+
+		method := strings.ToLower(normalizeMethod(route.Method))
+		op, ok := paths[specPath][method].(map[string]any)
+		if !ok {
+			op = map[string]any{"responses": map[string]any{}}
+			if params := openAPIParams(full); len(params) > 0 {
+				op["parameters"] = params
+			}
+			paths[specPath][method] = op
+		}
+
+		defs := route.Responses
+		if len(defs) == 0 {
+			defs = []response{route.Response}
+		}
+		responses := op["responses"].(map[string]any)
+		for _, res := range defs {
+			addOpenAPIResponse(responses, res)
+		}
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Mocker API",
+			"version": appVersion,
+		},
+		"paths": paths,
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// openAPIParams describes the path params of a route path.
+func openAPIParams(path string) []any {
+	var params []any
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]any{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	return params
+}
+
+// addOpenAPIResponse adds a response object for res, keeping the first one
+// when several variants share a status code.
+func addOpenAPIResponse(responses map[string]any, res response) {
+	status := res.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	code := strconv.Itoa(status)
+	if _, exists := responses[code]; exists {
+		return
+	}
+
+	description := http.StatusText(status)
+	if description == "" {
+		description = "Response " + code
+	}
+	out := map[string]any{"description": description}
+
+	switch {
+	case res.BodyBase64 != "":
+		contentType := res.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		out["content"] = map[string]any{contentType: map[string]any{
+			"schema": map[string]any{"type": "string", "format": "binary"},
+		}}
+	case res.Body != nil:
+		out["content"] = map[string]any{"application/json": map[string]any{
+			"example": res.Body,
+		}}
+	}
+	responses[code] = out
+}
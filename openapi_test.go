@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteOpenAPI(t *testing.T) {
+	input := parseInput(t, `{"basePath": "/api", "routes": [
+		{"method": "GET", "path": "/users/{id:[0-9]+}", "response": {"status": 200, "body": {"id": 1}}},
+		{"method": "delete", "path": "/users/{id:[0-9]+}", "response": {"status": 204}},
+		{"method": "GET", "path": "/avatar", "response": {"status": 200, "bodyBase64": "AA==", "contentType": "image/png"}}
+	]}`)
+	path := filepath.Join(t.TempDir(), "openapi.json")
+	if err := writeOpenAPI(path, input); err != nil {
+		t.Fatalf("writeOpenAPI: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Parameters []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+			Responses map[string]map[string]any `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	if spec.OpenAPI != "3.0.3" {
+		t.Errorf("openapi = %q, want 3.0.3", spec.OpenAPI)
+	}
+	if len(spec.Paths) != 2 {
+		t.Errorf("paths = %v, want /api/users/{id} and /api/avatar", reflect.ValueOf(spec.Paths).MapKeys())
+	}
+
+	user := spec.Paths["/api/users/{id}"]
+	if len(user) != 2 {
+		t.Fatalf("/api/users/{id} has %d operations, want get and delete", len(user))
+	}
+	op := user["get"]
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "id" || op.Parameters[0].In != "path" {
+		t.Errorf("get parameters = %+v, want the id path param", op.Parameters)
+	}
+	example := op.Responses["200"]["content"].(map[string]any)["application/json"].(map[string]any)["example"]
+	if !reflect.DeepEqual(example, map[string]any{"id": float64(1)}) {
+		t.Errorf("get 200 example = %v, want {\"id\": 1}", example)
+	}
+	if _, ok := user["delete"].Responses["204"]; !ok {
+		t.Errorf("delete responses = %v, want 204", user["delete"].Responses)
+	}
+	if _, ok := spec.Paths["/api/avatar"]["get"].Responses["200"]["content"].(map[string]any)["image/png"]; !ok {
+		t.Error("binary response is not described as image/png")
+	}
+}
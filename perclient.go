@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// perClientType gives each distinct client its own response: the first
+// client seen gets the first entry, the second client the second, and so on,
+// wrapping around once every entry is taken. Assignments are kept in memory,
+// so a client keeps seeing the same entry; a client idle for
+// perClientIdleTTL is forgotten and counts as new when it comes back.
+//
+// Clients are told apart by IP (the forwarded one with -trust-proxy) or by
+// the value of a request header.
+//
+// Example JSON fragment:
+//
+//	"perClient": {
+//	  "key": "header:X-Tenant",
+//	  "responses": [
+//	    { "status": 200, "body": { "tenant": "A" } },
+//	    { "status": 200, "body": { "tenant": "B" } }
+//	  ]
+//	}
+type perClientType struct {
+	Key       string     `json:"key"`       // "ip" (default) or "header:NAME"
+	Responses []response `json:"responses"` // Entries assigned to clients in order of first appearance
+}
+
+// perClientIdleTTL is how long a client's assignment outlives its last
+// request.
+const perClientIdleTTL = 24 * time.Hour
+
+// perClientPicker holds the client -> entry assignments of a route.
+type perClientPicker struct {
+	header    string // header name; "" keys clients by IP
+	responses []preparedResponse
+
+	mu       sync.Mutex
+	assigned *idleMap[string, int]
+	next     int // entry assigned to the next new client
+}
+
+// newPerClientPicker validates the config and prepares its responses.
+func newPerClientPicker(def *perClientType) (*perClientPicker, error) {
+	p := &perClientPicker{assigned: newIdleMap[string, int](perClientIdleTTL)}
+	switch key := strings.TrimSpace(def.Key); {
+	case key == "" || key == "ip":
+	case strings.HasPrefix(key, "header:") && strings.TrimSpace(key[len("header:"):]) != "":
+		p.header = strings.TrimSpace(key[len("header:"):])
+	default:
+		return nil, fmt.Errorf(`perClient.key must be "ip" or "header:NAME", got %q`, def.Key)
+	}
+	if len(def.Responses) == 0 {
+		return nil, fmt.Errorf("perClient.responses must not be empty")
+	}
+	for i, res := range def.Responses {
+		prepared, err := prepareResponse(res)
+		if err != nil {
+			return nil, fmt.Errorf("perClient.responses[%d]: %w", i, err)
+		}
+		p.responses = append(p.responses, prepared)
+	}
+	return p, nil
+}
+
+// pick returns the response assigned to the request's client, assigning the
+// next one when the client is new.
+func (p *perClientPicker) pick(r *http.Request) preparedResponse {
+	key := r.RemoteAddr
+	if p.header != "" {
+		key = r.Header.Get(p.header)
+	} else if ip, ok := clientIP(r); ok {
+		key = ip.String()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	i, ok := p.assigned.get(key, now)
+	if !ok {
+		i = p.next
+		p.next = (p.next + 1) % len(p.responses)
+	}
+	p.assigned.set(key, i, now)
+	return p.responses[i]
+}
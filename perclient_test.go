@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPerClientHeader(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/dashboard", "perClient": {
+		"key": "header:X-Tenant",
+		"responses": [{"status": 200, "body": "A"}, {"status": 200, "body": "B"}]
+	}}]}`)
+
+	call := func(tenant string) string {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/dashboard", nil)
+		req.Header.Set("X-Tenant", tenant)
+		_, body := do(t, req)
+		return body
+	}
+	for i, tt := range []struct{ tenant, want string }{
+		{"acme", `"A"`},
+		{"globex", `"B"`},
+		{"acme", `"A"`},
+		{"initech", `"A"`}, // wraps around
+		{"globex", `"B"`},
+		{"initech", `"A"`},
+	} {
+		if got := call(tt.tenant); got != tt.want {
+			t.Errorf("call %d from %s: body = %s, want %s", i+1, tt.tenant, got, tt.want)
+		}
+	}
+}
+
+func TestPerClientIP(t *testing.T) {
+	input := parseInput(t, `{"routes": [{"method": "GET", "path": "/dashboard", "perClient": {
+		"responses": [{"status": 200, "body": "A"}, {"status": 200, "body": "B"}]
+	}}]}`)
+	input.TrustProxy = true
+	srv := serveInput(t, input)
+
+	for _, tt := range []struct{ ip, want string }{
+		{"198.51.100.1", `"A"`},
+		{"198.51.100.2", `"B"`},
+		{"198.51.100.1", `"A"`},
+		{"198.51.100.2", `"B"`},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/dashboard", nil)
+		req.Header.Set("X-Forwarded-For", tt.ip)
+		if _, body := do(t, req); body != tt.want {
+			t.Errorf("client %s: body = %s, want %s", tt.ip, body, tt.want)
+		}
+	}
+}
+
+func TestNewPerClientPickerErrors(t *testing.T) {
+	for _, tt := range []struct {
+		def  perClientType
+		want string
+	}{
+		{perClientType{Key: "cookie:session", Responses: []response{{Status: 200}}}, "perClient.key"},
+		{perClientType{Key: "header: ", Responses: []response{{Status: 200}}}, "perClient.key"},
+		{perClientType{Key: "ip"}, "must not be empty"},
+		{perClientType{Responses: []response{{Status: 200, BodyBase64: "%%"}}}, "perClient.responses[0]"},
+	} {
+		if _, err := newPerClientPicker(&tt.def); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.def, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// transformType enables the optional stages applied to a JSON response body.
+//
+// The stages always run in this order, each on the output of the previous
+// one:
+//
+//  1. base: body (or the rows of bodyCsv)
+//  2. templates: string values containing {{ }} are rendered as templates;
+//     path params are available as .Params
+//  3. params: "{id}" in string values becomes the path param value
+//  4. merge: the merge object is deep-merged over the body
+//  5. fromBody: fields are set from the request body
+//  6. overridableFields: query params replace single fields
+//
+// Templates are parsed from the config at startup and params are substituted
+// into their output, so a path param is never parsed as a template.
+//
+// Example JSON fragment (GET /api/users/42 answers with "id": "42", a fresh
+// "fetchedAt" and the merged "source"):
+//
+//	"response": {
+//	  "status": 200,
+//	  "body": { "id": "{id}", "fetchedAt": "{{now}}" },
+//	  "transform": { "params": true, "templates": true, "merge": { "source": "mock" } }
+//	}
+//
+// bodyBase64, bodyTemplate, echoWithMerge and ndjson responses are served as
+// they are and skip the pipeline.
+type transformType struct {
+	Params    bool           `json:"params"`    // Substitute {param} placeholders in string values
+	Templates bool           `json:"templates"` // Render string values as templates (see templateFuncs)
+	Merge     map[string]any `json:"merge"`     // Object deep-merged over the body; string values may use templates
+
+	// FromBody sets response fields (dot-separated) from the JSON request
+	// body, e.g. {"received": {"path": "$.id", "default": null}} answers a
+	// webhook posting {"id": 42} with "received": 42.
+	FromBody map[string]fromBodyType `json:"fromBody"`
+}
+
+// fromBodyType picks a value out of the request body.
+type fromBodyType struct {
+	Path    string `json:"path"`    // JSONPath in the request body, e.g. "$.data.id"
+	Default any    `json:"default"` // Used when the path is missing (null when unset)
+}
+
+// prepareTransform parses the body templates of a transform block and prepares
+// its merge object.
+func prepareTransform(p *preparedResponse) error {
+	t := p.Transform
+	if t.Templates {
+		body, err := prepareMerge(p.Body)
+		if err != nil {
+			return fmt.Errorf("invalid transform.templates: %w", err)
+		}
+		p.transformBody = body
+		if l := p.localized; l != nil {
+			l.templated = make([]any, len(l.bodies))
+			for i, body := range l.bodies {
+				if l.templated[i], err = prepareMerge(body); err != nil {
+					return fmt.Errorf("invalid transform.templates: %w", err)
+				}
+			}
+		}
+	}
+	if t.Merge != nil {
+		merge, err := prepareMerge(t.Merge)
+		if err != nil {
+			return fmt.Errorf("invalid transform.merge: %w", err)
+		}
+		p.transformMerge = merge
+	}
+	for field, from := range t.FromBody {
+		if _, err := parseJSONPath(from.Path); err != nil {
+			return fmt.Errorf("invalid transform.fromBody.%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// transform runs the enabled pipeline stages on the response body. The
+// configured body is never modified; every stage works on a copy.
+func (h *routeHandler) transform(r *http.Request, res preparedResponse) (any, error) {
+	t := res.Transform
+	body := res.Body
+	if t == nil {
+		return body, nil
+	}
+
+	var data *templateData
+	if t.Templates || res.transformMerge != nil || len(t.FromBody) > 0 {
+		var err error
+		if data, err = newTemplateData(r, h.state); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Templates {
+		var err error
+		if body, err = renderMerge(res.transformBody, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if replacer := paramReplacer(r); t.Params && replacer != nil {
+		var err error
+		body, err = mapStrings(body, func(s string) (any, error) {
+			return replacer.Replace(s), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if res.transformMerge != nil {
+		merge, err := renderMerge(res.transformMerge, data)
+		if err != nil {
+			return nil, err
+		}
+		if body, err = cloneJSON(body); err != nil {
+			return nil, err
+		}
+		body = mergeJSON(body, merge)
+	}
+
+	if len(t.FromBody) > 0 {
+		if res.transformMerge == nil { // the merge stage already copied the body
+			var err error
+			if body, err = cloneJSON(body); err != nil {
+				return nil, err
+			}
+		}
+		obj, ok := body.(map[string]any)
+		if !ok {
+			obj = map[string]any{}
+		}
+		for field, from := range t.FromBody {
+			value, _ := evalJSONPath(data.Body, from.Path) // validated at startup
+			if value == nil {
+				value = from.Default
+			}
+			setNestedField(obj, field, value)
+		}
+		body = obj
+	}
+	return body, nil
+}
+
+// paramReplacer returns a replacer turning "{name}" into the value of the
+// request's path param name, or nil when the route has no params.
+func paramReplacer(r *http.Request) *strings.Replacer {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || len(rctx.URLParams.Keys) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, 2*len(rctx.URLParams.Keys))
+	for i, key := range rctx.URLParams.Keys {
+		pairs = append(pairs, "{"+key+"}", rctx.URLParams.Values[i])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// mapStrings returns a copy of a decoded JSON value with fn applied to every
+// string value (object keys are left alone).
+func mapStrings(v any, fn func(string) (any, error)) (any, error) {
+	switch node := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for key, value := range node {
+			mapped, err := mapStrings(value, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = mapped
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(node))
+		for i, value := range node {
+			mapped, err := mapStrings(value, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = mapped
+		}
+		return out, nil
+	case string:
+		return fn(node)
+	default:
+		return v, nil
+	}
+}
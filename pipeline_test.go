@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransformParamsAndMerge(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/api/users/{id}", "response": {"status": 200,
+		"body": {"id": "{id}", "links": {"self": "/api/users/{id}"}, "source": "base"},
+		"transform": {"params": true, "merge": {"source": "mock", "links": {"orders": "/api/orders?user=1"}}}}}]}`)
+
+	_, body := get(t, srv.URL+"/api/users/42")
+	var got any
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("body %s: %v", body, err)
+	}
+	want := map[string]any{
+		"id":     "42",
+		"links":  map[string]any{"self": "/api/users/42", "orders": "/api/orders?user=1"},
+		"source": "mock",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("body = %v, want %v", got, want)
+	}
+
+	// The configured body is not modified by a request.
+	_, body = get(t, srv.URL+"/api/users/7")
+	if err := json.Unmarshal([]byte(body), &got); err != nil || got.(map[string]any)["id"] != "7" {
+		t.Errorf("second request body = %s, want id 7", body)
+	}
+}
+
+func TestTransformStagesOrder(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "POST", "path": "/orders/{id}", "overridableFields": {"note": "note"},
+		"response": {"status": 200,
+		"body": {"id": "{id}", "note": "", "total": "{{jsonpath \"total\" | json}}"},
+		"transform": {"params": true, "templates": true, "merge": {"total": "merged"}, "fromBody": {"total": {"path": "$.total"}}}}}]}`)
+
+	_, body := post(t, srv.URL+"/orders/9?note=rush", `{"total": 12.5}`)
+	want := `{"id":"9","note":"rush","total":12.5}`
+	if body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestTransformFromBody(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "POST", "path": "/webhooks", "response": {"status": 200,
+		"body": {"ok": true},
+		"transform": {"fromBody": {
+			"received": {"path": "$.id"},
+			"meta.event": {"path": "$.data.type", "default": "unknown"},
+			"firstTag": {"path": "$.tags[0]", "default": null}
+		}}}}]}`)
+
+	for _, tt := range []struct{ body, want string }{
+		{`{"id": 42, "data": {"type": "order.paid"}, "tags": ["a", "b"]}`, `{"firstTag":"a","meta":{"event":"order.paid"},"ok":true,"received":42}`},
+		{`{"id": "evt_1"}`, `{"firstTag":null,"meta":{"event":"unknown"},"ok":true,"received":"evt_1"}`},
+		{`not json`, `{"firstTag":null,"meta":{"event":"unknown"},"ok":true,"received":null}`},
+	} {
+		if _, body := post(t, srv.URL+"/webhooks", tt.body); body != tt.want {
+			t.Errorf("posting %s: body = %s, want %s", tt.body, body, tt.want)
+		}
+	}
+}
+
+func TestTransformFromBodyInvalidPath(t *testing.T) {
+	_, err := BuildRouter(parseInput(t, `{"routes": [{"method": "POST", "path": "/webhooks", "response": {"status": 200,
+		"transform": {"fromBody": {"received": {"path": "$.items[x]"}}}}}]}`))
+	if err == nil || !strings.Contains(err.Error(), "transform.fromBody.received") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTransformParamsNotTemplated(t *testing.T) {
+	t.Setenv("MOCKER_TEST_SECRET", "s3cret")
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/api/users/{id}", "response": {"status": 200,
+		"body": {"id": "{id}", "user": "user-{{.Params.id}}"},
+		"transform": {"params": true, "templates": true}}}]}`)
+
+	// A path param that looks like a template is substituted as text, never
+	// rendered.
+	_, body := get(t, srv.URL+"/api/users/%7B%7Benv%20%22MOCKER_TEST_SECRET%22%7D%7D")
+	if strings.Contains(body, "s3cret") {
+		t.Fatalf("body = %s: the path param was rendered as a template", body)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("body %s: %v", body, err)
+	}
+	if want := `{{env "MOCKER_TEST_SECRET"}}`; got["id"] != want || got["user"] != "user-"+want {
+		t.Errorf("body = %v, want the literal param %s", got, want)
+	}
+
+	_, body = get(t, srv.URL+"/api/users/42")
+	if want := `{"id":"42","user":"user-42"}`; body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestTransformTemplatesLocalized(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/greeting/{name}", "response": {"status": 200,
+		"body": {"message": "Hello {{.Params.name}}"},
+		"localized": {"fr": {"message": "Bonjour {{.Params.name}}"}},
+		"transform": {"templates": true}}}]}`)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/greeting/Ada", nil)
+	req.Header.Set("Accept-Language", "fr")
+	if _, body := do(t, req); body != `{"message":"Bonjour Ada"}` {
+		t.Errorf("fr body = %s, want the rendered French body", body)
+	}
+	if _, body := get(t, srv.URL+"/greeting/Ada"); body != `{"message":"Hello Ada"}` {
+		t.Errorf("default body = %s, want the rendered default body", body)
+	}
+}
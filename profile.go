@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	httppprof "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiling starts a CPU profile when cpuPath is set and returns a
+// function that stops it and, when memPath is set, writes a heap profile.
+// Call the returned function once the server has shut down.
+//
+// With both paths empty nothing is profiled and the returned function is a
+// no-op.
+func startProfiling(cpuPath, memPath string) (func(), error) {
+	var cpuFile *os.File
+	if cpuPath != "" {
+		f, err := os.Create(cpuPath)
+		if err != nil {
+			return nil, fmt.Errorf("creating CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("starting CPU profile: %w", err)
+		}
+		cpuFile = f
+	}
+
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			cpuFile.Close()
+			fmt.Printf("CPU profile written to %s\n", cpuPath)
+		}
+		if memPath != "" {
+			if err := writeHeapProfile(memPath); err != nil {
+				log.Printf("error in writing memory profile, err: %s", err.Error())
+				return
+			}
+			fmt.Printf("memory profile written to %s\n", memPath)
+		}
+	}, nil
+}
+
+// writeHeapProfile writes an up-to-date heap profile to path.
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runtime.GC() // get up-to-date statistics
+	return pprof.WriteHeapProfile(f)
+}
+
+// pprofMux returns a mux serving the net/http/pprof endpoints under
+// /debug/pprof/. It is kept apart from the mock router so profiling never
+// shadows a mocked route.
+func pprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+	return mux
+}
+
+// servePprof serves the pprof endpoints on port until ctx is cancelled.
+func servePprof(ctx context.Context, port string) {
+	srv := &http.Server{Addr: ":" + port, Handler: pprofMux()}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+
+	fmt.Printf("pprof is available at http://localhost:%s/debug/pprof/\n", port)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("pprof server error: %s", err.Error())
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStartProfiling(t *testing.T) {
+	dir := t.TempDir()
+	cpuPath := filepath.Join(dir, "cpu.prof")
+	memPath := filepath.Join(dir, "mem.prof")
+
+	stop, err := startProfiling(cpuPath, memPath)
+	if err != nil {
+		t.Fatalf("startProfiling: %v", err)
+	}
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/work", "response": {"status": 200, "body": "done"}}]}`)
+	for range 50 {
+		get(t, srv.URL+"/work")
+	}
+	stop()
+
+	for _, path := range []string{cpuPath, memPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Errorf("profile not written: %v", err)
+		} else if info.Size() == 0 {
+			t.Errorf("%s is empty", filepath.Base(path))
+		}
+	}
+}
+
+func TestStartProfilingDisabled(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	stop, err := startProfiling("", "")
+	if err != nil {
+		t.Fatalf("startProfiling: %v", err)
+	}
+	stop()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("profiling without paths wrote %d files", len(entries))
+	}
+}
+
+func TestServePprof(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenPort(ln)
+	ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go servePprof(ctx, port)
+
+	var res *http.Response
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if res, err = http.Get("http://127.0.0.1:" + port + "/debug/pprof/"); err == nil || time.Now().After(deadline) {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("pprof port not reachable: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Errorf("pprof index status = %d, want 200", res.StatusCode)
+	}
+
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/work", "response": {"status": 200}}]}`)
+	if res, _ := get(t, srv.URL+"/debug/pprof/"); res.StatusCode != 404 {
+		t.Errorf("mock router serves /debug/pprof/ with status %d, want 404", res.StatusCode)
+	}
+}
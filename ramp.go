@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// rampDelayType delays a route's responses by an amount interpolated
+// linearly from StartMs on the first request to EndMs on request number
+// Requests and after, e.g. to simulate a cold backend warming up.
+//
+// Example JSON fragment (2s on the first call, 50ms from the 10th on):
+//
+//	"rampDelay": { "startMs": 2000, "endMs": 50, "requests": 10 }
+type rampDelayType struct {
+	StartMs  int `json:"startMs"`  // Delay of the first request, in milliseconds
+	EndMs    int `json:"endMs"`    // Delay once Requests requests were made, in milliseconds
+	Requests int `json:"requests"` // Number of requests to interpolate over
+}
+
+// validateRampDelay checks a rampDelay block.
+func validateRampDelay(r *rampDelayType) error {
+	if r.StartMs < 0 || r.EndMs < 0 {
+		return fmt.Errorf("rampDelay: startMs and endMs must not be negative")
+	}
+	if r.Requests < 1 {
+		return fmt.Errorf("rampDelay: requests must be at least 1")
+	}
+	return nil
+}
+
+// delay returns the delay for the n-th request (1-based).
+func (r *rampDelayType) delay(n int64) time.Duration {
+	if n >= int64(r.Requests) || r.Requests == 1 {
+		return time.Duration(r.EndMs) * time.Millisecond
+	}
+	step := float64(r.EndMs-r.StartMs) / float64(r.Requests-1)
+	ms := float64(r.StartMs) + step*float64(n-1)
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// waitRamp sleeps for the route's ramp delay, returning early when the
+// client goes away.
+func (h *routeHandler) waitRamp(r *http.Request) {
+	sleepRequest(r, h.route.RampDelay.delay(h.rampCalls.Add(1)))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRampDelay(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/cold", "rampDelay": {"startMs": 300, "endMs": 0, "requests": 3},
+		"response": {"status": 200}}]}`)
+
+	var took []time.Duration
+	for range 4 {
+		start := time.Now()
+		get(t, srv.URL+"/cold")
+		took = append(took, time.Since(start))
+	}
+	if took[0] < 300*time.Millisecond {
+		t.Errorf("first request took %s, want at least 300ms", took[0])
+	}
+	if took[3] >= took[0]/2 {
+		t.Errorf("fourth request took %s, want it much faster than the first (%s)", took[3], took[0])
+	}
+}
+
+func TestRampDelayInterpolation(t *testing.T) {
+	r := &rampDelayType{StartMs: 1000, EndMs: 100, Requests: 4}
+	want := []time.Duration{1000, 700, 400, 100, 100}
+	for i, ms := range want {
+		if got := r.delay(int64(i + 1)); got != ms*time.Millisecond {
+			t.Errorf("delay(%d) = %s, want %s", i+1, got, ms*time.Millisecond)
+		}
+	}
+	if err := validateRampDelay(&rampDelayType{StartMs: 10, Requests: 0}); err == nil {
+		t.Error("validateRampDelay accepted requests 0")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// rng is the random source behind every random choice Mocker makes.
+// A *rand.Rand is not safe for concurrent use, hence the mutex.
+var (
+	rngMu sync.Mutex
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// seedRandom reseeds rng so random choices repeat across runs (-seed).
+func seedRandom(seed int64) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	rng = rand.New(rand.NewSource(seed))
+}
+
+// randIntn returns a random int in [0, n).
+func randIntn(n int) int {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return rng.Intn(n)
+}
+
+// randFloat64 returns a random float64 in [0, 1).
+func randFloat64() float64 {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return rng.Float64()
+}
+
+// randNormFloat64 returns a normally distributed float64 with mean 0 and
+// standard deviation 1.
+func randNormFloat64() float64 {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return rng.NormFloat64()
+}
+
+// randRead fills b with random bytes.
+func randRead(b []byte) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	_, _ = rng.Read(b)
+}
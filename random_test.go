@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeedRandom(t *testing.T) {
+	t.Cleanup(func() { seedRandom(time.Now().UnixNano()) })
+	bodies := make([]string, 10)
+	for i := range bodies {
+		bodies[i] = fmt.Sprintf(`{"status": 200, "body": %d}`, i)
+	}
+	config := `{"routes": [{"method": "GET", "path": "/pick", "response": {"status": 200},
+		"chaosResponses": [` + strings.Join(bodies, ",") + `]}]}`
+
+	run := func() []string {
+		input := parseInput(t, config)
+		input.Chaos = true
+		srv := serveInput(t, input)
+		seedRandom(42)
+		var got []string
+		for range 20 {
+			_, body := get(t, srv.URL+"/pick")
+			got = append(got, body)
+		}
+		return got
+	}
+	first, second := run(), run()
+	if !slices.Equal(first, second) {
+		t.Errorf("same seed gave different responses:\n%v\n%v", first, second)
+	}
+	if slices.Equal(first, slices.Repeat(first[:1], len(first))) {
+		t.Errorf("seeded run always picked %s", first[0])
+	}
+}
+
+func TestSeedRandomUUID(t *testing.T) {
+	t.Cleanup(func() { seedRandom(time.Now().UnixNano()) })
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/id",
+		"response": {"status": 200, "headers": {"X-Request-Id": "{{uuid}}"}}}]}`)
+
+	run := func() []string {
+		seedRandom(42)
+		var got []string
+		for range 3 {
+			res, _ := get(t, srv.URL+"/id")
+			got = append(got, res.Header.Get("X-Request-Id"))
+		}
+		return got
+	}
+	first, second := run(), run()
+	if !slices.Equal(first, second) {
+		t.Errorf("same seed gave different UUIDs:\n%v\n%v", first, second)
+	}
+	if first[0] == first[1] {
+		t.Errorf("seeded run repeated UUID %s", first[0])
+	}
+}
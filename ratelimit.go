@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitType caps how many requests a route accepts within a sliding
+// window; excess requests get 429 until the oldest ones leave the window.
+//
+// Example JSON fragment (at most 60 requests in any 60 s):
+//
+//	"rateLimit": { "windowMs": 60000, "maxRequests": 60 }
+//
+// Every response carries X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (Unix time in seconds when a slot frees up); a 429 also
+// carries Retry-After.
+type rateLimitType struct {
+	WindowMs    int64 `json:"windowMs"`    // Length of the sliding window in milliseconds
+	MaxRequests int   `json:"maxRequests"` // Requests accepted within any window
+}
+
+// slidingWindow keeps the times of the requests accepted within the window.
+type slidingWindow struct {
+	window time.Duration
+	max    int
+
+	mu       sync.Mutex
+	accepted []time.Time // oldest first
+}
+
+// newSlidingWindow validates a rateLimit block.
+func newSlidingWindow(def *rateLimitType) (*slidingWindow, error) {
+	if def.WindowMs <= 0 {
+		return nil, fmt.Errorf("rateLimit.windowMs must be positive, got %d", def.WindowMs)
+	}
+	if def.MaxRequests <= 0 {
+		return nil, fmt.Errorf("rateLimit.maxRequests must be positive, got %d", def.MaxRequests)
+	}
+	return &slidingWindow{window: time.Duration(def.WindowMs) * time.Millisecond, max: def.MaxRequests}, nil
+}
+
+// allow records a request at now if the window has room. It returns the
+// requests left afterwards and when the oldest accepted one leaves the
+// window.
+func (s *slidingWindow) allow(now time.Time) (ok bool, remaining int, reset time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := now.Add(-s.window)
+	expired := 0
+	for expired < len(s.accepted) && !s.accepted[expired].After(cutoff) {
+		expired++
+	}
+	s.accepted = s.accepted[expired:]
+
+	ok = len(s.accepted) < s.max
+	if ok {
+		s.accepted = append(s.accepted, now)
+	}
+	return ok, s.max - len(s.accepted), s.accepted[0].Add(s.window)
+}
+
+// limit applies the window to the request, setting the rate limit headers.
+// It reports whether the request was rejected (and answered with 429).
+func (s *slidingWindow) limit(w http.ResponseWriter, now time.Time) (bool, error) {
+	ok, remaining, reset := s.allow(now)
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.max))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(ceilUnix(reset), 10))
+	if ok {
+		return false, nil
+	}
+	retry := int64(reset.Sub(now)/time.Second) + 1
+	w.Header().Set("Retry-After", strconv.FormatInt(retry, 10))
+	return true, respondWithJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
+}
+
+// ceilUnix returns t in Unix seconds, rounded up.
+func ceilUnix(t time.Time) int64 {
+	if t.Nanosecond() > 0 {
+		return t.Unix() + 1
+	}
+	return t.Unix()
+}
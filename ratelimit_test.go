@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	state := newRouterState()
+	start := time.Unix(1700000000, 0)
+	now := start
+	state.now = func() time.Time { return now }
+	h := newTestRouteHandler(t, `{"method": "GET", "path": "/quota",
+		"rateLimit": {"windowMs": 60000, "maxRequests": 3},
+		"response": {"status": 200, "body": "ok"}}`, state)
+
+	for _, tt := range []struct {
+		after     time.Duration // since start
+		status    int
+		remaining int
+		reset     int64 // seconds after start
+	}{
+		{0, 200, 2, 60},
+		{10 * time.Second, 200, 1, 60},
+		{20 * time.Second, 200, 0, 60},
+		{30 * time.Second, 429, 0, 60},
+		{59 * time.Second, 429, 0, 60},
+		{60 * time.Second, 200, 0, 70}, // the first request left the window
+		{61 * time.Second, 429, 0, 70},
+		{90 * time.Second, 200, 1, 120}, // the 10s and 20s ones left too
+	} {
+		now = start.Add(tt.after)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/quota", nil))
+		if rec.Code != tt.status {
+			t.Errorf("at +%s: status %d, want %d", tt.after, rec.Code, tt.status)
+		}
+		header := rec.Header()
+		if got := header.Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("at +%s: X-RateLimit-Limit = %q", tt.after, got)
+		}
+		if got := header.Get("X-RateLimit-Remaining"); got != strconv.Itoa(tt.remaining) {
+			t.Errorf("at +%s: X-RateLimit-Remaining = %q, want %d", tt.after, got, tt.remaining)
+		}
+		if got := header.Get("X-RateLimit-Reset"); got != strconv.FormatInt(start.Unix()+tt.reset, 10) {
+			t.Errorf("at +%s: X-RateLimit-Reset = %q, want start+%d", tt.after, got, tt.reset)
+		}
+		if retry := header.Get("Retry-After"); (tt.status == 429) != (retry != "") {
+			t.Errorf("at +%s: Retry-After = %q", tt.after, retry)
+		}
+	}
+}
+
+func TestNewSlidingWindowErrors(t *testing.T) {
+	for _, def := range []rateLimitType{{WindowMs: 0, MaxRequests: 1}, {WindowMs: 1000, MaxRequests: 0}} {
+		if _, err := newSlidingWindow(&def); err == nil {
+			t.Errorf("%+v accepted", def)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exampleType is a sample request for a route, e.g. as captured by -record.
+// It does not take part in matching; -curl-out sends it instead of its
+// generated placeholder request.
+//
+// Example JSON fragment:
+//
+//	"example": {
+//	  "query": "page=2",
+//	  "headers": { "Accept": "application/json" },
+//	  "body": { "name": "Ada" }
+//	}
+type exampleType struct {
+	Query   string            `json:"query,omitempty"`   // Raw query string, without "?"
+	Headers map[string]string `json:"headers,omitempty"` // Request headers
+	Body    any               `json:"body,omitempty"`    // JSON body, or a string sent as is
+}
+
+// defaultRecordStrip lists the headers -record leaves out of the recording
+// when -record-strip is not set: credentials and session cookies.
+const defaultRecordStrip = "Authorization,Proxy-Authorization,Cookie,Set-Cookie"
+
+// recordRequestSkip lists request headers not worth recording: they are set
+// by the client library or the transport rather than by the API call.
+var recordRequestSkip = map[string]bool{
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Te":                true,
+	"Upgrade":           true,
+}
+
+// recorder proxies every request to an upstream and records the first
+// exchange per method+path as a route.
+type recorder struct {
+	proxy  *httputil.ReverseProxy
+	strip  map[string]bool // canonical names of headers left out of the recording
+	bodies bool            // record request bodies in the route example
+
+	mu     sync.Mutex
+	routes []scaffoldRoute
+	seen   map[string]bool
+}
+
+// recordedRequestKey is the context key under which ServeHTTP passes the
+// captured request on to capture.
+type recordedRequestKey struct{}
+
+// newRecorder returns a recorder proxying to upstream. Headers named in strip
+// (case-insensitive) are dropped from recorded requests and responses; they
+// are still forwarded.
+func newRecorder(upstream string, strip []string, bodies bool) (*recorder, error) {
+	target, err := url.Parse(upstream)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid -record URL %q", upstream)
+	}
+	rec := &recorder{strip: map[string]bool{}, bodies: bodies, seen: map[string]bool{}}
+	for _, name := range strip {
+		if name = strings.TrimSpace(name); name != "" {
+			rec.strip[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	rec.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			// Let the transport negotiate (and undo) compression so the
+			// recorded bodies are plain.
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		ModifyResponse: rec.capture,
+	}
+	return rec, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := scaffoldRoute{Method: normalizeMethod(r.Method), Path: r.URL.EscapedPath()}
+	example := &exampleType{Query: r.URL.RawQuery}
+	for name, values := range r.Header {
+		if rec.strip[name] || recordRequestSkip[name] || len(values) == 0 {
+			continue
+		}
+		if example.Headers == nil {
+			example.Headers = map[string]string{}
+		}
+		example.Headers[name] = values[0]
+	}
+	if rec.bodies {
+		body, err := readRequestBody(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch {
+		case len(body) == 0:
+		case json.Valid(body):
+			example.Body = json.RawMessage(body)
+		default:
+			example.Body = string(body)
+		}
+	}
+	if example.Query != "" || example.Headers != nil || example.Body != nil {
+		route.Example = example
+	}
+	rec.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), recordedRequestKey{}, route)))
+}
+
+// capture records the upstream response of the first exchange per
+// method+path, leaving the response itself intact for the client.
+func (rec *recorder) capture(resp *http.Response) error {
+	route, ok := resp.Request.Context().Value(recordedRequestKey{}).(scaffoldRoute)
+	if !ok {
+		return nil
+	}
+	key := route.Method + " " + route.Path
+	rec.mu.Lock()
+	seen := rec.seen[key]
+	rec.seen[key] = true
+	rec.mu.Unlock()
+	if seen {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	route.Response = scaffoldResponse{Status: resp.StatusCode}
+	route.Response.setBody(body, resp.Header.Get("Content-Type"))
+	for name, values := range resp.Header {
+		if harHeaderSkip[name] || traceHeaderSkip[name] || rec.strip[name] || len(values) == 0 {
+			continue
+		}
+		if route.Response.Headers == nil {
+			route.Response.Headers = map[string]string{}
+		}
+		route.Response.Headers[name] = values[0]
+	}
+
+	rec.mu.Lock()
+	rec.routes = append(rec.routes, route)
+	rec.mu.Unlock()
+	fmt.Printf("⏺️  Recorded %s %s (%d)\n", route.Method, route.Path, resp.StatusCode)
+	return nil
+}
+
+// config returns the recorded routes as a config, sorted by path and method.
+func (rec *recorder) config() scaffoldConfig {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	routes := append([]scaffoldRoute{}, rec.routes...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return scaffoldConfig{Port: defaultPort, Routes: routes}
+}
+
+// runRecorder serves the recording proxy on addr until interrupted (or until
+// maxRequests requests, when positive), then writes the recorded routes to
+// outPath and returns their number.
+func runRecorder(rec *recorder, addr, outPath string, maxRequests int64, shutdownTimeout time.Duration) (int, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var handler http.Handler = rec
+	if maxRequests > 0 {
+		handler = limitRequests(rec, maxRequests, cancel)
+	}
+	fmt.Printf("⏺️  Recording through port %s; stop with Ctrl+C to write the config\n", listenPort(ln))
+	if err := runServer(ctx, &http.Server{Handler: handler}, ln, "", "", shutdownTimeout); err != nil {
+		return 0, err
+	}
+
+	config := rec.config()
+	if len(config.Routes) == 0 {
+		return 0, errors.New("no requests were recorded")
+	}
+	return len(config.Routes), writeScaffold(outPath, config)
+}
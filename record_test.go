@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestRecorder starts an upstream answering every request with a JSON body
+// and a session cookie, and a recorder proxying to it.
+func newTestRecorder(t *testing.T, strip []string, bodies bool) (*recorder, *httptest.Server, *http.Header) {
+	t.Helper()
+	forwarded := &http.Header{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*forwarded = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Request-Id", "42")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	t.Cleanup(upstream.Close)
+	rec, err := newRecorder(upstream.URL, strip, bodies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(rec)
+	t.Cleanup(proxy.Close)
+	return rec, proxy, forwarded
+}
+
+func TestRecordStripsHeaders(t *testing.T) {
+	rec, proxy, forwarded := newTestRecorder(t, strings.Split(defaultRecordStrip, ","), true)
+
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/users?notify=1", strings.NewReader(`{"name":"Ada"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Tenant", "acme")
+	res, body := do(t, req)
+	if res.StatusCode != 201 || body != `{"id":1}` || res.Header.Get("Set-Cookie") == "" {
+		t.Fatalf("proxied response = %d %s %v, want the upstream's untouched", res.StatusCode, body, res.Header)
+	}
+	if forwarded.Get("Authorization") != "Bearer secret" {
+		t.Error("Authorization was not forwarded upstream")
+	}
+
+	config := rec.config()
+	if len(config.Routes) != 1 {
+		t.Fatalf("recorded %d routes, want 1", len(config.Routes))
+	}
+	route := config.Routes[0]
+	if route.Method != "POST" || route.Path != "/users" || route.Response.Status != 201 {
+		t.Errorf("route = %s %s %d, want POST /users 201", route.Method, route.Path, route.Response.Status)
+	}
+	if route.Example == nil || route.Example.Query != "notify=1" || route.Example.Headers["X-Tenant"] != "acme" {
+		t.Fatalf("example = %+v, want the query and X-Tenant", route.Example)
+	}
+	if _, ok := route.Example.Headers["Authorization"]; ok {
+		t.Error("Authorization was recorded")
+	}
+	if _, ok := route.Response.Headers["Set-Cookie"]; ok {
+		t.Error("Set-Cookie was recorded")
+	}
+	if route.Response.Headers["X-Request-Id"] != "42" {
+		t.Errorf("response headers = %v, want X-Request-Id kept", route.Response.Headers)
+	}
+	if got, _ := json.Marshal(route.Example.Body); string(got) != `{"name":"Ada"}` {
+		t.Errorf("example body = %s, want the request body", got)
+	}
+}
+
+func TestRecordCustomStrip(t *testing.T) {
+	rec, proxy, _ := newTestRecorder(t, []string{" x-tenant "}, false)
+
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/users", strings.NewReader(`{"name":"Ada"}`))
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Tenant", "acme")
+	do(t, req)
+	do(t, req) // only the first exchange per method+path is recorded
+
+	config := rec.config()
+	if len(config.Routes) != 1 {
+		t.Fatalf("recorded %d routes, want 1", len(config.Routes))
+	}
+	example := config.Routes[0].Example
+	if example == nil || example.Headers["Authorization"] != "Bearer token" {
+		t.Errorf("example = %+v, want Authorization kept with a custom -record-strip", example)
+	}
+	if _, ok := example.Headers["X-Tenant"]; ok {
+		t.Error("X-Tenant was recorded")
+	}
+	if example.Body != nil {
+		t.Errorf("example body = %v, want none without -record-bodies", example.Body)
+	}
+}
+
+func TestNewRecorderInvalidURL(t *testing.T) {
+	if _, err := newRecorder("localhost:8080", nil, false); err == nil {
+		t.Error("URL without a scheme: want an error")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// releasesAPI is the GitHub API endpoint for Mocker releases. It is a
+// variable so tests can point it at a stub server.
+var releasesAPI = "https://api.github.com/repos/Yuddhaa/mocker/releases"
+
+// latestRelease returns the tag name of the latest published release.
+func latestRelease(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesAPI+"/latest", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP %d from %s", resp.StatusCode, req.URL)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", err
+	}
+	return release.TagName, nil
+}
+
+// releaseInfo is the part of a GitHub release listed by -list-versions.
+type releaseInfo struct {
+	TagName     string    `json:"tag_name"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// linkNextPattern extracts the "next" URL from a GitHub Link header.
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// listReleases returns every published release, newest first, following the
+// API's Link header across pages.
+func listReleases(ctx context.Context) ([]releaseInfo, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	var releases []releaseInfo
+	next := releasesAPI + "?per_page=100"
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page []releaseInfo
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected HTTP %d from %s", resp.StatusCode, req.URL)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, page...)
+
+		next = ""
+		if m := linkNextPattern.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			next = m[1]
+		}
+	}
+	return releases, nil
+}
+
+// printReleases writes one line per release with its publish date, marking
+// the running version.
+func printReleases(w io.Writer, releases []releaseInfo) {
+	if len(releases) == 0 {
+		fmt.Fprintln(w, "No releases found.")
+		return
+	}
+	for _, r := range releases {
+		marker := " "
+		if r.TagName == appVersion {
+			marker = "*"
+		}
+		published := "unpublished"
+		if !r.PublishedAt.IsZero() {
+			published = r.PublishedAt.Format("2006-01-02")
+		}
+		line := fmt.Sprintf("%s %-12s %s", marker, r.TagName, published)
+		if r.TagName == appVersion {
+			line += " (current)"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// notifyUpdates checks for a newer release right away and then every
+// interval until ctx is cancelled, writing a one-line notice to w the first
+// time each new version is seen. Failed checks are silently retried on the next
+// tick so serving is never disturbed.
+func notifyUpdates(ctx context.Context, w io.Writer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	notified := ""
+	for {
+		if tag, err := latestRelease(ctx); err == nil && tag != "" && tag != appVersion && tag != notified {
+			fmt.Fprintf(w, "🆕 Mocker %s is available (current: %s). Update with: mocker --update\n", tag, appVersion)
+			notified = tag
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubReleases points releasesAPI at handler for the rest of the test.
+func stubReleases(t *testing.T, handler http.Handler) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	old := releasesAPI
+	releasesAPI = srv.URL + "/releases"
+	t.Cleanup(func() { releasesAPI = old })
+}
+
+func TestNotifyUpdates(t *testing.T) {
+	var checks atomic.Int32
+	stubReleases(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks.Add(1)
+		fmt.Fprint(w, `{"tag_name": "v99.0.0"}`)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		notifyUpdates(ctx, &out, 5*time.Millisecond)
+		close(done)
+	}()
+	for deadline := time.Now().Add(2 * time.Second); checks.Load() < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d release checks within 2s", checks.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notifyUpdates did not stop after cancellation")
+	}
+
+	if n := strings.Count(out.String(), "v99.0.0 is available"); n != 1 {
+		t.Errorf("notice printed %d times, want once:\n%s", n, out.String())
+	}
+}
+
+func TestListReleases(t *testing.T) {
+	var base string
+	stubReleases(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"tag_name": "v0.9.0", "published_at": "2025-01-02T10:00:00Z"}]`)
+			return
+		}
+		w.Header().Set("Link", `<`+base+`/releases?page=2>; rel="next"`)
+		fmt.Fprintf(w, `[{"tag_name": "v2.0.0", "published_at": "2026-03-01T10:00:00Z"}, {"tag_name": %q, "published_at": "2025-06-01T10:00:00Z"}]`, appVersion)
+	}))
+	base = strings.TrimSuffix(releasesAPI, "/releases")
+
+	releases, err := listReleases(context.Background())
+	if err != nil {
+		t.Fatalf("listReleases: %v", err)
+	}
+	var out bytes.Buffer
+	printReleases(&out, releases)
+	want := "  v2.0.0       2026-03-01\n" +
+		"* " + fmt.Sprintf("%-12s", appVersion) + " 2025-06-01 (current)\n" +
+		"  v0.9.0       2025-01-02\n"
+	if out.String() != want {
+		t.Errorf("printed:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestListReleasesErrors(t *testing.T) {
+	stubReleases(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	if _, err := listReleases(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("HTTP 403: err = %v, want one naming the status", err)
+	}
+
+	releasesAPI = "http://127.0.0.1:1/releases" // restored by stubReleases
+	if _, err := listReleases(context.Background()); err == nil {
+		t.Error("unreachable API: listReleases did not fail")
+	}
+}
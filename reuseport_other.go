@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePortSupported reports whether -reuse-port works on this platform.
+const reusePortSupported = false
+
+// setReusePort is unavailable outside Unix-like systems: Windows has no
+// SO_REUSEPORT equivalent with the same load-sharing semantics.
+func setReusePort(network, address string, c syscall.RawConn) error {
+	return errors.New("SO_REUSEPORT is not supported on this platform")
+}
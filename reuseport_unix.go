@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePortSupported reports whether -reuse-port works on this platform.
+const reusePortSupported = true
+
+// setReusePort enables SO_REUSEPORT on the listening socket so another Mocker
+// process can bind the same port, e.g. to hand over traffic during a restart.
+func setReusePort(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
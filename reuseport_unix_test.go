@@ -0,0 +1,24 @@
+//go:build unix
+
+package main
+
+import "testing"
+
+func TestListenReusePort(t *testing.T) {
+	first, err := listen("127.0.0.1:0", true)
+	if err != nil {
+		t.Fatalf("first listener: %v", err)
+	}
+	defer first.Close()
+
+	second, err := listen(first.Addr().String(), true)
+	if err != nil {
+		t.Fatalf("second listener on %s: %v", first.Addr(), err)
+	}
+	second.Close()
+
+	if ln, err := listen(first.Addr().String(), false); err == nil {
+		ln.Close()
+		t.Errorf("listener without -reuse-port bound %s too", first.Addr())
+	}
+}
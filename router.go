@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// BuildRouter wires every route in the given config into a chi router and
+// returns it as a plain http.Handler.
+//
+// Keeping this separate from main lets Go tests embed Mocker in-process:
+//
+//	handler, err := BuildRouter(input)
+//	srv := httptest.NewServer(handler)
+//
+// It returns an error if any route cannot be set up (e.g. invalid bodyBase64).
+func BuildRouter(input inputType) (http.Handler, error) {
+	state := newRouterState()
+	var h http.Handler
+	var err error
+	if len(input.Scenarios) == 0 {
+		h, err = buildMux(input, state)
+	} else {
+		h, err = newScenarioRouter(input, state)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if h, err = withRoutesDump(h, input); err != nil {
+		return nil, err
+	}
+	if input.Metrics != nil {
+		h = withMetrics(h, input.Metrics)
+	}
+	if input.MaxConcurrent > 0 {
+		h = limitConcurrency(h, input.MaxConcurrent, input.RejectOverflow)
+	}
+	if input.MaxHeaderBytes > 0 {
+		h = limitHeaderBytes(h, input.MaxHeaderBytes)
+	}
+	return h, nil
+}
+
+// buildMux wires the routes of a single route set into a chi router.
+// Routes in the same router share state (e.g. the in-memory store).
+func buildMux(input inputType, state *routerState) (http.Handler, error) {
+	if err := validateRoutes(input); err != nil {
+		return nil, err
+	}
+
+	router := chi.NewRouter()
+	if input.TrustProxy {
+		router.Use(forwardedClientIP)
+	}
+	logOut := input.LogOutput
+	if logOut == nil {
+		logOut = os.Stdout
+	}
+	router.Use(requestLogger(logOut, newLogOptions(input)))
+	if input.Trace != nil {
+		router.Use(recordTrace(input.Trace))
+	}
+	if input.Metrics != nil {
+		router.Use(observeMetrics(input.Metrics))
+	}
+	router.Use(recoverer(input.ErrorResponse))
+	router.Use(traceContext)
+	if input.NoServerHeaders {
+		router.Use(serverHeaders(""))
+	} else if input.ServerHeader != "" {
+		router.Use(serverHeaders(input.ServerHeader))
+	}
+	if input.CORSReflectOrigin {
+		router.Use(corsReflectOrigin)
+	}
+	if len(input.DefaultHeaders) > 0 {
+		router.Use(defaultHeaders(input.DefaultHeaders))
+	}
+	// Compression is applied per route (see compressLevel) rather than
+	// router-wide so routes can opt out; unrouted responses follow -compress.
+	compressed := withCompression(input.CompressLevel)
+	if input.NotFoundResponse != nil {
+		router.NotFound(compressed(notFoundHandler(*input.NotFoundResponse)).ServeHTTP)
+	}
+
+	// With strict methods every known path first gets a catch-all 405
+	// handler; the per-method registrations below then take precedence.
+	if input.StrictMethods {
+		for path, methods := range allowedMethods(input) {
+			router.Handle(path, compressed(methodNotAllowed(methods)))
+		}
+	}
+
+	// Routes sharing method+path are grouped so their match blocks can pick
+	// the variant per request; chi only allows one handler per pair.
+	groups := map[string]*routeGroup{}
+	for _, route := range input.Routes {
+		route.Method = normalizeMethod(route.Method)
+		route.Path = input.fullPath(route.Path)
+		if !input.Chaos {
+			route.ChaosResponses = nil
+		}
+		h, err := newRouteHandler(route, state)
+		if err != nil {
+			return nil, err
+		}
+
+		key := route.Method + " " + route.Path
+		g, ok := groups[key]
+		if !ok {
+			g = &routeGroup{}
+			groups[key] = g
+			router.Method(route.Method, route.Path, g)
+		}
+		bandwidth := input.Bandwidth
+		if route.Bandwidth > 0 {
+			bandwidth = route.Bandwidth
+		}
+		g.add(h, withBandwidth(bandwidth)(withCompression(route.compressLevel(input.CompressLevel))(h)))
+		fmt.Printf("%v %v set\n", route.Method, route.Path)
+	}
+
+	if err := mountStatic(router, input.Static); err != nil {
+		return nil, err
+	}
+
+	// OPTIONS on a known path answers 204 with the configured methods, unless
+	// the config defines its own OPTIONS route there.
+	for path, methods := range allowedMethods(input) {
+		if !slices.Contains(methods, http.MethodOptions) {
+			router.Options(path, optionsResponder(methods))
+		}
+	}
+
+	return router, nil
+}
+
+// defaultCompressLevel is the -compress-level default, also used by routes
+// enabling compression with "compress": true when -compress is off.
+const defaultCompressLevel = 5
+
+// compressLevel returns the compression level for the route given the
+// global one (0 = off): its compress field overrides -compress either way.
+func (route routesType) compressLevel(global int) int {
+	switch {
+	case route.Compress == nil:
+		return global
+	case !*route.Compress:
+		return 0
+	case global > 0:
+		return global
+	default:
+		return defaultCompressLevel
+	}
+}
+
+// withCompression returns a middleware compressing responses at level, or
+// leaving them untouched when level is 0.
+func withCompression(level int) func(http.Handler) http.Handler {
+	if level <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return middleware.Compress(level)
+}
+
+// allowedMethods returns the configured methods for every full route path,
+// sorted and de-duplicated.
+func allowedMethods(input inputType) map[string][]string {
+	allowed := map[string][]string{}
+	for _, route := range input.Routes {
+		path := input.fullPath(route.Path)
+		method := normalizeMethod(route.Method)
+		if !slices.Contains(allowed[path], method) {
+			allowed[path] = append(allowed[path], method)
+		}
+	}
+	for _, methods := range allowed {
+		sort.Strings(methods)
+	}
+	return allowed
+}
+
+// methodNotAllowed responds with 405 and an Allow header listing methods.
+func methodNotAllowed(methods []string) http.Handler {
+	allow := strings.Join(methods, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		_ = respondWithJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method " + r.Method + " not allowed"})
+	})
+}
+
+// optionsResponder answers OPTIONS with 204 and an Allow header listing
+// methods and OPTIONS itself.
+func optionsResponder(methods []string) http.HandlerFunc {
+	allow := strings.Join(append(slices.Clone(methods), http.MethodOptions), ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// respondWithJSON marshals the given payload into JSON and writes it to the
+// HTTP response with the given status code.
+//
+// A Content-Type already set on the response (e.g. through configured
+// headers) is kept; otherwise application/json is used.
+//
+// It returns an error if the JSON marshaling fails.
+func respondWithJSON(w http.ResponseWriter, code int, payload any) error {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(code)
+	_, err = w.Write(response)
+	return err
+}
+
+// respondWithBytes writes raw bytes to the HTTP response with the given
+// status code and content type, setting Content-Length explicitly.
+//
+// A Content-Type already set on the response wins over contentType, and an
+// empty contentType defaults to application/octet-stream.
+func respondWithBytes(w http.ResponseWriter, code int, contentType string, body []byte) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+	_, err := w.Write(body)
+	return err
+}
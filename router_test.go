@@ -0,0 +1,215 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuildRouter(t *testing.T) {
+	input := inputType{
+		Routes: []routesType{{
+			Method:   "GET",
+			Path:     "/api/users/{id}",
+			Response: response{Status: 200, Body: map[string]any{"name": "Ada"}},
+		}},
+		LogOutput: io.Discard,
+	}
+	handler, err := BuildRouter(input)
+	if err != nil {
+		t.Fatalf("BuildRouter: %v", err)
+	}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	res, body := get(t, srv.URL+"/api/users/1")
+	if res.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", res.StatusCode)
+	}
+	if got := strings.TrimSpace(body); got != `{"name":"Ada"}` {
+		t.Errorf("body = %s, want {\"name\":\"Ada\"}", got)
+	}
+	if got := res.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	if res, _ := get(t, srv.URL+"/api/unknown"); res.StatusCode != 404 {
+		t.Errorf("unknown path status = %d, want 404", res.StatusCode)
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	srv := newTestServer(t, `{"basePath": "/api/v1/", "routes": [
+		{"method": "GET", "path": "/users", "response": {"status": 200, "body": "users"}}
+	]}`)
+
+	if res, _ := get(t, srv.URL+"/api/v1/users"); res.StatusCode != 200 {
+		t.Errorf("prefixed path: status = %d, want 200", res.StatusCode)
+	}
+	for _, path := range []string{"/users", "/api/v1//users"} {
+		if res, _ := get(t, srv.URL+path); res.StatusCode != 404 {
+			t.Errorf("%s: status = %d, want 404", path, res.StatusCode)
+		}
+	}
+}
+
+func TestFullPath(t *testing.T) {
+	tests := []struct{ base, path, want string }{
+		{"", "/users", "/users"},
+		{"/api/v1", "/users", "/api/v1/users"},
+		{"api/v1/", "users", "/api/v1/users"},
+		{"/api/v1/", "//users", "/api/v1/users"},
+		{"/", "/users", "/users"},
+	}
+	for _, tt := range tests {
+		if got := (inputType{BasePath: tt.base}).fullPath(tt.path); got != tt.want {
+			t.Errorf("fullPath(%q, %q) = %q, want %q", tt.base, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestStrictMethods(t *testing.T) {
+	input := parseInput(t, `{"routes": [
+		{"method": "GET", "path": "/items/{id}", "response": {"status": 200}},
+		{"method": "PUT", "path": "/items/{id}", "response": {"status": 204}}
+	]}`)
+	input.StrictMethods = true
+	srv := serveInput(t, input)
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/items/1", nil)
+	res, _ := do(t, req)
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE status = %d, want 405", res.StatusCode)
+	}
+	if got := res.Header.Get("Allow"); got != "GET, PUT" {
+		t.Errorf("Allow = %q, want \"GET, PUT\"", got)
+	}
+	if res, _ := get(t, srv.URL+"/items/1"); res.StatusCode != 200 {
+		t.Errorf("GET status = %d, want 200", res.StatusCode)
+	}
+	if res, _ := get(t, srv.URL+"/unknown"); res.StatusCode != 404 {
+		t.Errorf("unknown path status = %d, want 404", res.StatusCode)
+	}
+}
+
+func TestCompressLevel(t *testing.T) {
+	items := make([]string, 3000)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"id": %d, "score": %d}`, i, i*7919%10007)
+	}
+	config := `{"routes": [{"method": "GET", "path": "/items", "response": {"status": 200, "body": [` + strings.Join(items, ",") + `]}}]}`
+
+	sizes := map[int]int{}
+	for _, level := range []int{1, 9} {
+		input := parseInput(t, config)
+		input.CompressLevel = level
+		srv := serveInput(t, input)
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/items", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		res, body := do(t, req)
+		if got := res.Header.Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("level %d: Content-Encoding = %q, want gzip", level, got)
+		}
+		zr, err := gzip.NewReader(strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("level %d: %v", level, err)
+		}
+		plain, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("level %d: decompressing: %v", level, err)
+		}
+		if !json.Valid(plain) {
+			t.Errorf("level %d: decompressed body is not JSON", level)
+		}
+		sizes[level] = len(body)
+	}
+	if sizes[9] >= sizes[1] {
+		t.Errorf("level 9 output (%d bytes) is not smaller than level 1 output (%d bytes)", sizes[9], sizes[1])
+	}
+}
+
+func TestRouteCompressOverride(t *testing.T) {
+	body := `{"text": "` + strings.Repeat("compressible ", 200) + `"}`
+	config := `{"routes": [
+		{"method": "GET", "path": "/default", "response": {"status": 200, "body": ` + body + `}},
+		{"method": "GET", "path": "/off", "compress": false, "response": {"status": 200, "body": ` + body + `}},
+		{"method": "GET", "path": "/on", "compress": true, "response": {"status": 200, "body": ` + body + `}}
+	]}`
+
+	for _, tt := range []struct {
+		global int
+		want   map[string]string
+	}{
+		{6, map[string]string{"/default": "gzip", "/off": "", "/on": "gzip"}},
+		{0, map[string]string{"/default": "", "/off": "", "/on": "gzip"}},
+	} {
+		input := parseInput(t, config)
+		input.CompressLevel = tt.global
+		srv := serveInput(t, input)
+		for path, want := range tt.want {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			res, got := do(t, req)
+			if enc := res.Header.Get("Content-Encoding"); enc != want {
+				t.Errorf("global level %d, %s: Content-Encoding = %q, want %q", tt.global, path, enc, want)
+			}
+			if want == "" && !json.Valid([]byte(got)) {
+				t.Errorf("global level %d, %s: uncompressed body is not JSON", tt.global, path)
+			}
+		}
+	}
+}
+
+func TestCompressLevelOverride(t *testing.T) {
+	on, off := true, false
+	for _, tt := range []struct {
+		compress *bool
+		global   int
+		want     int
+	}{
+		{nil, 0, 0},
+		{nil, 4, 4},
+		{&off, 4, 0},
+		{&on, 4, 4},
+		{&on, 0, defaultCompressLevel},
+	} {
+		if got := (routesType{Compress: tt.compress}).compressLevel(tt.global); got != tt.want {
+			t.Errorf("compress %v, global %d: level %d, want %d", tt.compress, tt.global, got, tt.want)
+		}
+	}
+}
+
+func TestOptionsAllow(t *testing.T) {
+	srv := newTestServer(t, `{"basePath": "/api", "routes": [
+		{"method": "GET", "path": "/users", "response": {"status": 200}},
+		{"method": "post", "path": "/users", "response": {"status": 201}},
+		{"method": "GET", "path": "/teams", "response": {"status": 200}},
+		{"method": "OPTIONS", "path": "/teams", "response": {"status": 200, "body": "custom"}}
+	]}`)
+
+	options := func(path string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodOptions, srv.URL+path, nil)
+		return do(t, req)
+	}
+
+	res, body := options("/api/users")
+	if res.StatusCode != http.StatusNoContent || body != "" {
+		t.Errorf("OPTIONS /api/users: %d %q, want 204 without body", res.StatusCode, body)
+	}
+	if got := res.Header.Get("Allow"); got != "GET, POST, OPTIONS" {
+		t.Errorf("Allow = %q, want \"GET, POST, OPTIONS\"", got)
+	}
+
+	if res, body := options("/api/teams"); res.StatusCode != http.StatusOK || body != `"custom"` {
+		t.Errorf("configured OPTIONS route: %d %s, want 200 \"custom\"", res.StatusCode, body)
+	}
+	if res, _ := options("/api/unknown"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("OPTIONS on unknown path: %d, want 404", res.StatusCode)
+	}
+}
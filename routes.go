@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// routeTableEntry is one route of the table printed by -routes-json.
+type routeTableEntry struct {
+	Method string `json:"method"` // Normalized method, e.g. GET
+	Path   string `json:"path"`   // Full path including the base path
+	Status int    `json:"status"` // Status of the first response the route serves
+}
+
+// routeTable returns the effective routes of a loaded config (after
+// -override merges and the base path), in config order.
+func routeTable(input inputType) []routeTableEntry {
+	table := make([]routeTableEntry, 0, len(input.Routes))
+	for _, route := range input.Routes {
+		table = append(table, routeTableEntry{
+			Method: normalizeMethod(route.Method),
+			Path:   input.fullPath(route.Path),
+			Status: firstStatus(route),
+		})
+	}
+	return table
+}
+
+// firstStatus returns the status of the response served on the first call:
+// the "responses" entry with the lowest afterCalls, or "response".
+func firstStatus(route routesType) int {
+	if len(route.Responses) == 0 {
+		return route.Response.Status
+	}
+	first := route.Responses[0]
+	for _, res := range route.Responses[1:] {
+		if res.AfterCalls < first.AfterCalls {
+			first = res
+		}
+	}
+	return first.Status
+}
+
+// writeRoutesJSON writes the route table to w as an indented JSON array.
+func writeRoutesJSON(w io.Writer, input inputType) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(routeTable(input))
+}
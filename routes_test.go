@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteRoutesJSON(t *testing.T) {
+	input := parseInput(t, `{"basePath": "/api/v1", "routes": [
+		{"method": "get", "path": "/users", "response": {"status": 200}},
+		{"method": "POST", "path": "users", "responses": [
+			{"afterCalls": 3, "status": 429},
+			{"status": 201}
+		]}
+	]}`)
+
+	var out bytes.Buffer
+	if err := writeRoutesJSON(&out, input); err != nil {
+		t.Fatalf("writeRoutesJSON: %v", err)
+	}
+	want := `[
+  {
+    "method": "GET",
+    "path": "/api/v1/users",
+    "status": 200
+  },
+  {
+    "method": "POST",
+    "path": "/api/v1/users",
+    "status": 201
+  }
+]
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// scenarioType is a named set of routes that can be switched on as a whole,
+// e.g. "happy" vs "errors". Its routes are layered over the top-level routes:
+// same method+path replaces, anything else is added.
+//
+// Example JSON:
+//
+//	"scenarios": {
+//	  "errors": {
+//	    "routes": [
+//	      { "method": "GET", "path": "/api/users", "response": { "status": 500, "body": { "error": "boom" } } }
+//	    ]
+//	  }
+//	}
+type scenarioType struct {
+	Routes []routesType `json:"routes"` // Routes active in this scenario
+}
+
+// scenarioQueryParam and scenarioHeader select a scenario for one request.
+const (
+	scenarioQueryParam = "__scenario"
+	scenarioHeader     = "X-Mocker-Scenario"
+)
+
+// scenarioAdminPath switches the active scenario at runtime: POST
+// {"scenario": "errors"} to it ("" for the top-level routes alone), or GET it
+// to see the active one.
+const scenarioAdminPath = "/__scenario"
+
+// scenarioRouter serves each request from the router of the selected
+// scenario. The empty name is the top-level routes on their own.
+type scenarioRouter struct {
+	routers map[string]http.Handler
+	active  atomic.Value // string: scenario used when the request names none
+}
+
+// newScenarioRouter builds one router per scenario, all sharing state.
+func newScenarioRouter(input inputType, state *routerState) (*scenarioRouter, error) {
+	if input.Scenario != "" {
+		if _, ok := input.Scenarios[input.Scenario]; !ok {
+			return nil, fmt.Errorf("unknown scenario %q, available: %s", input.Scenario, strings.Join(scenarioNames(input), ", "))
+		}
+	}
+
+	sr := &scenarioRouter{routers: map[string]http.Handler{}}
+	sr.active.Store(input.Scenario)
+
+	names := append([]string{""}, scenarioNames(input)...)
+	for _, name := range names {
+		scoped := input
+		scoped.Scenarios = nil
+		if name != "" {
+			scoped.Routes = mergeRoutes(input.Routes, input.Scenarios[name].Routes, routeKey)
+			fmt.Printf("scenario %q:\n", name)
+		}
+		router, err := buildMux(scoped, state)
+		if err != nil {
+			if name != "" {
+				return nil, fmt.Errorf("scenario %q: %w", name, err)
+			}
+			return nil, err
+		}
+		sr.routers[name] = router
+	}
+	return sr, nil
+}
+
+// scenarioNames returns the configured scenario names in sorted order.
+func scenarioNames(input inputType) []string {
+	names := make([]string, 0, len(input.Scenarios))
+	for name := range input.Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP implements http.Handler. The scenario is taken from the
+// __scenario query param, then the X-Mocker-Scenario header, then the
+// active scenario (-scenario flag, or the last one set on scenarioAdminPath).
+func (sr *scenarioRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == scenarioAdminPath {
+		sr.serveAdmin(w, r)
+		return
+	}
+
+	name := r.URL.Query().Get(scenarioQueryParam)
+	if name == "" {
+		name = r.Header.Get(scenarioHeader)
+	}
+	if name == "" {
+		name = sr.active.Load().(string)
+	}
+
+	router, ok := sr.routers[name]
+	if !ok {
+		_ = respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown scenario " + name})
+		return
+	}
+	router.ServeHTTP(w, r)
+}
+
+// serveAdmin reports or switches the active scenario. The switch is a single
+// atomic store, so requests already being served finish on the old scenario
+// and every later one uses the new one.
+func (sr *scenarioRouter) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Scenario *string `json:"scenario"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Scenario == nil {
+			_ = respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": `expected a JSON body like {"scenario": "name"}`})
+			return
+		}
+		if _, ok := sr.routers[*req.Scenario]; !ok {
+			_ = respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown scenario " + *req.Scenario})
+			return
+		}
+		sr.active.Store(*req.Scenario)
+		fmt.Printf("scenario switched to %q\n", *req.Scenario)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		_ = respondWithJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	_ = respondWithJSON(w, http.StatusOK, map[string]string{"scenario": sr.active.Load().(string)})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// scenarioConfig has a happy top-level route and an "errors" scenario
+// replacing it.
+const scenarioConfig = `{
+	"routes": [
+		{"method": "GET", "path": "/api/users", "response": {"status": 200, "body": "happy"}},
+		{"method": "GET", "path": "/api/health", "response": {"status": 200, "body": "ok"}}
+	],
+	"scenarios": {
+		"errors": {"routes": [
+			{"method": "GET", "path": "/api/users", "response": {"status": 500, "body": "boom"}}
+		]}
+	}
+}`
+
+func TestScenarioSelection(t *testing.T) {
+	srv := newTestServer(t, scenarioConfig)
+
+	check := func(name string, req *http.Request, status int, body string) {
+		t.Helper()
+		res, got := do(t, req)
+		if res.StatusCode != status || strings.TrimSpace(got) != body {
+			t.Errorf("%s: got %d %s, want %d %s", name, res.StatusCode, got, status, body)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/users", nil)
+	check("default", req, 200, `"happy"`)
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/api/users?__scenario=errors", nil)
+	check("query", req, 500, `"boom"`)
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/api/users", nil)
+	req.Header.Set("X-Mocker-Scenario", "errors")
+	check("header", req, 500, `"boom"`)
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/api/health?__scenario=errors", nil)
+	check("inherited route", req, 200, `"ok"`)
+}
+
+func TestScenarioFlag(t *testing.T) {
+	input := parseInput(t, scenarioConfig)
+	input.Scenario = "errors"
+	srv := serveInput(t, input)
+	if res, _ := get(t, srv.URL+"/api/users"); res.StatusCode != 500 {
+		t.Errorf("-scenario=errors: status = %d, want 500", res.StatusCode)
+	}
+
+	input.Scenario = "missing"
+	if _, err := BuildRouter(input); err == nil || !strings.Contains(err.Error(), "errors") {
+		t.Errorf("unknown scenario: err = %v, want one listing the available scenarios", err)
+	}
+}
+
+func TestScenarioAdmin(t *testing.T) {
+	srv := newTestServer(t, scenarioConfig)
+
+	if res, body := post(t, srv.URL+"/__scenario", `{"scenario": "errors"}`); res.StatusCode != 200 || !strings.Contains(body, `"errors"`) {
+		t.Fatalf("switch: got %d %s", res.StatusCode, body)
+	}
+	if res, _ := get(t, srv.URL+"/api/users"); res.StatusCode != 500 {
+		t.Errorf("after switch: status = %d, want 500", res.StatusCode)
+	}
+	if res, body := get(t, srv.URL+"/__scenario"); res.StatusCode != 200 || !strings.Contains(body, `"errors"`) {
+		t.Errorf("GET: got %d %s, want the active scenario", res.StatusCode, body)
+	}
+
+	if res, _ := post(t, srv.URL+"/__scenario", `{"scenario": "missing"}`); res.StatusCode != 400 {
+		t.Errorf("unknown scenario: status = %d, want 400", res.StatusCode)
+	}
+	if res, _ := post(t, srv.URL+"/__scenario", `not json`); res.StatusCode != 400 {
+		t.Errorf("bad body: status = %d, want 400", res.StatusCode)
+	}
+	if res, _ := post(t, srv.URL+"/__scenario", `{"scenario": ""}`); res.StatusCode != 200 {
+		t.Errorf("back to default: status = %d, want 200", res.StatusCode)
+	}
+	if res, _ := get(t, srv.URL+"/api/users"); res.StatusCode != 200 {
+		t.Errorf("after reset: status = %d, want 200", res.StatusCode)
+	}
+}
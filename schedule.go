@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// scheduleType makes a route answer differently outside opening hours,
+// judged by the server clock.
+//
+// Within the schedule the route's normal response is served, otherwise
+// closedResponse. An end before start spans midnight (e.g. 22:00-06:00).
+//
+// Example JSON fragment:
+//
+//	"schedule": {
+//	  "days": ["mon", "tue", "wed", "thu", "fri"],
+//	  "start": "09:00",
+//	  "end": "17:30",
+//	  "timezone": "Europe/Berlin",
+//	  "closedResponse": { "status": 503, "body": { "status": "closed" } }
+//	}
+type scheduleType struct {
+	Days           []string `json:"days"`           // Open days: mon..sun or monday..sunday (default: every day)
+	Start          string   `json:"start"`          // Opening time, HH:MM
+	End            string   `json:"end"`            // Closing time, HH:MM (exclusive)
+	Timezone       string   `json:"timezone"`       // IANA time zone (default: the server's local zone)
+	ClosedResponse response `json:"closedResponse"` // Served outside the schedule (status defaults to 503)
+}
+
+// preparedSchedule is a validated scheduleType.
+type preparedSchedule struct {
+	days       map[time.Weekday]bool // nil means every day
+	start, end int                   // minutes since midnight
+	loc        *time.Location
+	closed     preparedResponse
+}
+
+// weekdays maps the accepted day names, full or abbreviated to three
+// letters, to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// prepareSchedule validates a schedule and prepares its closed response.
+func prepareSchedule(s *scheduleType) (*preparedSchedule, error) {
+	p := &preparedSchedule{loc: time.Local}
+	if len(s.Days) > 0 {
+		p.days = map[time.Weekday]bool{}
+		for _, day := range s.Days {
+			d, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("schedule: unknown day %q", day)
+			}
+			p.days[d] = true
+		}
+	}
+
+	var err error
+	if p.start, err = parseClock(s.Start); err != nil {
+		return nil, fmt.Errorf("schedule.start: %w", err)
+	}
+	if p.end, err = parseClock(s.End); err != nil {
+		return nil, fmt.Errorf("schedule.end: %w", err)
+	}
+	if s.Timezone != "" {
+		if p.loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, fmt.Errorf("schedule.timezone: %w", err)
+		}
+	}
+	closed := s.ClosedResponse
+	if closed.Status == 0 {
+		closed.Status = http.StatusServiceUnavailable
+	}
+	if p.closed, err = prepareResponse(closed); err != nil {
+		return nil, fmt.Errorf("schedule.closedResponse: %w", err)
+	}
+	return p, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("want HH:MM, got %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// open reports whether now falls within the schedule. For windows spanning
+// midnight the day is the one the window started on.
+func (p *preparedSchedule) open(now time.Time) bool {
+	now = now.In(p.loc)
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+
+	switch {
+	case p.start == p.end:
+		// Open all day.
+	case p.start < p.end:
+		if minute < p.start || minute >= p.end {
+			return false
+		}
+	default:
+		if minute >= p.end && minute < p.start {
+			return false
+		}
+		if minute < p.end {
+			day = (day + 6) % 7 // still the previous day's window
+		}
+	}
+	return p.days == nil || p.days[day]
+}
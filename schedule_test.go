@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	state := newRouterState()
+	var now time.Time
+	state.now = func() time.Time { return now }
+	h := newTestRouteHandler(t, `{"method": "GET", "path": "/shop",
+		"schedule": {"days": ["Mon", "tue", "Wednesday", "thu", "fri"], "start": "09:00", "end": "17:30", "timezone": "UTC",
+			"closedResponse": {"status": 503, "body": "closed"}},
+		"response": {"status": 200, "body": "open"}}`, state)
+
+	for _, tt := range []struct {
+		now  string
+		want string
+	}{
+		{"2026-10-12T09:00:00Z", `"open"`},   // Monday, opening minute
+		{"2026-10-16T17:29:59Z", `"open"`},   // Friday, just before closing
+		{"2026-10-16T17:30:00Z", `"closed"`}, // closing time is exclusive
+		{"2026-10-14T08:59:00Z", `"closed"`},
+		{"2026-10-17T12:00:00Z", `"closed"`},      // Saturday
+		{"2026-10-12T10:00:00+02:00", `"closed"`}, // 08:00 UTC
+	} {
+		now, _ = time.Parse(time.RFC3339, tt.now)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shop", nil))
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("at %s: body = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleClosedDefaultStatus(t *testing.T) {
+	state := newRouterState()
+	state.now = func() time.Time { return time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC) } // Saturday
+	h := newTestRouteHandler(t, `{"method": "GET", "path": "/shop",
+		"schedule": {"days": ["mon"], "start": "09:00", "end": "17:00", "timezone": "UTC",
+			"closedResponse": {"body": "closed"}},
+		"response": {"status": 200, "body": "open"}}`, state)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shop", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != `"closed"` {
+		t.Errorf("closed response: %d %s, want 503 \"closed\"", rec.Code, rec.Body.String())
+	}
+}
+
+func TestScheduleOvernight(t *testing.T) {
+	p, err := prepareSchedule(&scheduleType{Days: []string{"fri"}, Start: "22:00", End: "06:00", Timezone: "UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for now, want := range map[string]bool{
+		"2026-10-16T23:00:00Z": true,  // Friday night
+		"2026-10-17T05:59:00Z": true,  // Saturday morning, still Friday's window
+		"2026-10-17T06:00:00Z": false, // window over
+		"2026-10-17T23:00:00Z": false, // Saturday night
+		"2026-10-16T05:00:00Z": false, // Thursday's window
+	} {
+		at, _ := time.Parse(time.RFC3339, now)
+		if got := p.open(at); got != want {
+			t.Errorf("open(%s) = %v, want %v", now, got, want)
+		}
+	}
+}
+
+func TestPrepareScheduleErrors(t *testing.T) {
+	for _, tt := range []struct {
+		schedule scheduleType
+		want     string
+	}{
+		{scheduleType{Days: []string{"someday"}, Start: "09:00", End: "17:00"}, "unknown day"},
+		{scheduleType{Days: []string{"tuesXYZ"}, Start: "09:00", End: "17:00"}, "unknown day"},
+		{scheduleType{Days: []string{"tu"}, Start: "09:00", End: "17:00"}, "unknown day"},
+		{scheduleType{Start: "9am", End: "17:00"}, "schedule.start"},
+		{scheduleType{Start: "09:00", End: "25:00"}, "schedule.end"},
+		{scheduleType{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"}, "schedule.timezone"},
+	} {
+		if _, err := prepareSchedule(&tt.schedule); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.schedule, err, tt.want)
+		}
+	}
+}
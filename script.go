@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// bodyScript is a compiled response bodyScript: a Starlark (Python syntax)
+// program computing the response per request, run by go.starlark.net.
+//
+// The script sees a frozen dict named request with method, path, query,
+// headers, params (path params) and body (the JSON request body, or None),
+// and answers by assigning top-level variables:
+//
+//   - body: the JSON response body (required)
+//   - status: the status code (default: the response's status)
+//   - headers: a dict of extra response headers
+//
+// Example:
+//
+//	n = int(request["query"].get("n", "1"))
+//	if n < 0:
+//	    status = 400
+//	    body = {"error": "n must not be negative"}
+//	else:
+//	    body = {"double": n * 2}
+//
+// Top-level if, for and while statements and reassigning globals are
+// allowed. Scripts are sandboxed: load is not available, Starlark itself
+// has no access to the filesystem, network or process, and a run is
+// cancelled once it exceeds its timeout.
+type bodyScript struct {
+	program *starlark.Program
+	timeout time.Duration
+}
+
+// defaultScriptTimeout bounds a bodyScript run unless scriptTimeoutMs is set.
+const defaultScriptTimeout = 100 * time.Millisecond
+
+// maxScriptDepth bounds the nesting of the body a script returns, which also
+// rules out lists that contain themselves.
+const maxScriptDepth = 64
+
+// scriptOptions are the Starlark dialect of bodyScripts: a script is a flat
+// program, so control flow and reassignment are allowed at top level.
+var scriptOptions = &syntax.FileOptions{
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+}
+
+// compileScript parses and resolves a bodyScript.
+func compileScript(src string, timeout time.Duration) (*bodyScript, error) {
+	isPredeclared := func(name string) bool { return name == "request" }
+	_, program, err := starlark.SourceProgramOptions(scriptOptions, "bodyScript", src, isPredeclared)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = defaultScriptTimeout
+	}
+	return &bodyScript{program: program, timeout: timeout}, nil
+}
+
+// scriptResult is what a script assigned to body, status and headers.
+type scriptResult struct {
+	body    any
+	status  int // 0 when the script did not set it
+	headers map[string]string
+}
+
+// run executes the script for the request.
+func (s *bodyScript) run(r *http.Request, state *routerState) (scriptResult, error) {
+	var res scriptResult
+	data, err := newTemplateData(r, state)
+	if err != nil {
+		return res, err
+	}
+	request := starlark.NewDict(6)
+	_ = request.SetKey(starlark.String("method"), starlark.String(data.Method))
+	_ = request.SetKey(starlark.String("path"), starlark.String(data.Path))
+	for name, values := range map[string]map[string]string{"query": data.Query, "headers": data.Headers, "params": data.Params} {
+		d := starlark.NewDict(len(values))
+		for _, key := range sortedKeys(values) {
+			_ = d.SetKey(starlark.String(key), starlark.String(values[key]))
+		}
+		_ = request.SetKey(starlark.String(name), d)
+	}
+	_ = request.SetKey(starlark.String("body"), toStarlark(data.Body))
+	request.Freeze()
+
+	thread := &starlark.Thread{Name: "bodyScript", Print: func(*starlark.Thread, string) {}}
+	timer := time.AfterFunc(s.timeout, func() { thread.Cancel("script timed out") })
+	defer timer.Stop()
+	globals, err := s.program.Init(thread, starlark.StringDict{"request": request})
+	if err != nil {
+		return res, fmt.Errorf("bodyScript: %w", err)
+	}
+
+	body, ok := globals["body"]
+	if !ok {
+		return res, errors.New("bodyScript: the script did not assign body")
+	}
+	if res.body, err = fromStarlark(body, 0); err != nil {
+		return res, fmt.Errorf("bodyScript: body: %w", err)
+	}
+	if status, ok := globals["status"]; ok {
+		var code int
+		if err := starlark.AsInt(status, &code); err != nil || code < 100 || code > 999 {
+			return res, fmt.Errorf("bodyScript: status must be an int between 100 and 999, got %s", status)
+		}
+		res.status = code
+	}
+	if headers, ok := globals["headers"]; ok {
+		d, isDict := headers.(*starlark.Dict)
+		if !isDict {
+			return res, fmt.Errorf("bodyScript: headers must be a dict, got %s", headers.Type())
+		}
+		res.headers = map[string]string{}
+		for _, item := range d.Items() {
+			key, isString := starlark.AsString(item[0])
+			if !isString {
+				return res, fmt.Errorf("bodyScript: headers: key %s is not a string", item[0])
+			}
+			value, isString := starlark.AsString(item[1])
+			if !isString {
+				value = item[1].String()
+			}
+			res.headers[key] = value
+		}
+	}
+	return res, nil
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toStarlark converts decoded JSON into Starlark values. Integral numbers
+// become ints and object keys are inserted in sorted order.
+func toStarlark(v any) starlark.Value {
+	switch node := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		d := starlark.NewDict(len(node))
+		for _, key := range keys {
+			_ = d.SetKey(starlark.String(key), toStarlark(node[key]))
+		}
+		return d
+	case []any:
+		items := make([]starlark.Value, len(node))
+		for i, item := range node {
+			items[i] = toStarlark(item)
+		}
+		return starlark.NewList(items)
+	case string:
+		return starlark.String(node)
+	case bool:
+		return starlark.Bool(node)
+	case float64:
+		if node == math.Trunc(node) && math.Abs(node) < 1<<53 {
+			return starlark.MakeInt64(int64(node))
+		}
+		return starlark.Float(node)
+	}
+	return starlark.None
+}
+
+// fromStarlark converts a Starlark value into plain JSON-encodable values.
+// Tuples become arrays; dict keys must be strings.
+func fromStarlark(v starlark.Value, depth int) (any, error) {
+	if depth > maxScriptDepth {
+		return nil, fmt.Errorf("value nested more than %d levels deep", maxScriptDepth)
+	}
+	switch node := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(node), nil
+	case starlark.Int:
+		if n, ok := node.Int64(); ok {
+			return n, nil
+		}
+		return json.Number(node.BigInt().String()), nil
+	case starlark.Float:
+		f := float64(node)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("%s is not a JSON number", node)
+		}
+		return f, nil
+	case starlark.String:
+		return string(node), nil
+	case *starlark.Dict:
+		out := make(map[string]any, node.Len())
+		for _, item := range node.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("dict key %s is not a string", item[0])
+			}
+			value, err := fromStarlark(item[1], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = value
+		}
+		return out, nil
+	case *starlark.List, starlark.Tuple:
+		seq := node.(starlark.Indexable)
+		out := make([]any, seq.Len())
+		for i := range out {
+			item, err := fromStarlark(seq.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = item
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot return a %s", v.Type())
+}
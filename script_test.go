@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBodyScript(t *testing.T) {
+	script := strings.Join([]string{
+		`n = int(request["query"].get("n", "1"))`,
+		`if n < 0:`,
+		`    status = 400`,
+		`    body = {"error": "n must not be negative"}`,
+		`else:`,
+		`    body = {"double": n * 2, "name": request["params"]["name"].upper()}`,
+		`    headers = {"X-Double": str(n * 2)}`,
+	}, `\n`)
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/double/{name}", "response": {"status": 200,
+		"bodyScript": "`+strings.ReplaceAll(script, `"`, `\"`)+`"}}]}`)
+
+	for _, tt := range []struct {
+		query  string
+		status int
+		body   string
+		header string
+	}{
+		{"?n=21", 200, `{"double":42,"name":"ADA"}`, "42"},
+		{"", 200, `{"double":2,"name":"ADA"}`, "2"},
+		{"?n=-1", 400, `{"error":"n must not be negative"}`, ""},
+	} {
+		res, body := get(t, srv.URL+"/double/ada"+tt.query)
+		if res.StatusCode != tt.status || body != tt.body {
+			t.Errorf("%q: %d %s, want %d %s", tt.query, res.StatusCode, body, tt.status, tt.body)
+		}
+		if got := res.Header.Get("X-Double"); got != tt.header {
+			t.Errorf("%q: X-Double = %q, want %q", tt.query, got, tt.header)
+		}
+	}
+}
+
+func TestBodyScriptTimeout(t *testing.T) {
+	s, err := compileScript("n = 0\nfor i in range(100000):\n    for j in range(100000):\n        n += 1\nbody = n\n", 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := s.run(httptest.NewRequest(http.MethodGet, "/", nil), newRouterState()); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("script ran for %s despite the 20ms timeout", elapsed)
+	}
+}
+
+func TestCompileScriptErrors(t *testing.T) {
+	for _, src := range []string{
+		"body = (1",
+		"if True\n    body = 1\n",
+		"import os\n",
+		"body = open('/etc/passwd')\n",
+	} {
+		s, err := compileScript(src, 0)
+		if err == nil {
+			_, err = s.run(httptest.NewRequest(http.MethodGet, "/", nil), newRouterState())
+		}
+		if err == nil {
+			t.Errorf("%q: no error", src)
+		}
+	}
+}
+
+func TestBodyScriptErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, src, want string
+	}{
+		{"no body", "status = 201\n", "did not assign body"},
+		{"cyclic body", "l = []\nl.append(l)\nbody = l\n", "nested more than"},
+		{"non-string key", "body = {1: 'one'}\n", "not a string"},
+		{"function body", "body = len\n", "cannot return"},
+		{"bad status", "status = 'ok'\nbody = 1\n", "status must be an int"},
+		{"headers not a dict", "headers = ['X-A']\nbody = 1\n", "headers must be a dict"},
+		{"frozen request", "request['method'] = 'POST'\nbody = 1\n", "frozen"},
+	} {
+		s, err := compileScript(tt.src, time.Second)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		_, err = s.run(httptest.NewRequest(http.MethodGet, "/", nil), newRouterState())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestBodyScriptRequestBody(t *testing.T) {
+	s, err := compileScript("total = 0\nfor item in request['body']['items']:\n    total += item['price']\nbody = {'total': total, 'currency': request['body'].get('currency', 'EUR')}\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"items": [{"price": 3}, {"price": 4.5}]}`))
+	res, err := s.run(req, newRouterState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := json.Marshal(res.body); string(got) != `{"currency":"EUR","total":7.5}` {
+		t.Errorf("body = %s", got)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sequenceType makes a route one step of a multi-request flow that must be
+// called in order, per client session. A step is accepted only right after
+// the previous one, otherwise the route answers 409 Conflict; step 1 is always
+// accepted and starts the flow over.
+//
+// Example JSON fragments, on three routes:
+//
+//	"sequence": { "name": "checkout", "step": 1 }   // POST /cart
+//	"sequence": { "name": "checkout", "step": 2 }   // POST /checkout
+//	"sequence": { "name": "checkout", "step": 3 }   // POST /pay
+//
+// Sessions are told apart by a request header, X-Session-Id by default.
+type sequenceType struct {
+	Name   string `json:"name"`   // Flow the step belongs to; shared by its routes
+	Step   int    `json:"step"`   // Position of this route in the flow, from 1
+	Header string `json:"header"` // Request header identifying the session (default: X-Session-Id)
+}
+
+// defaultSequenceHeader identifies sessions when sequence.header is not set.
+const defaultSequenceHeader = "X-Session-Id"
+
+// validateSequence checks a sequence block and fills in its default header.
+func validateSequence(seq *sequenceType) error {
+	if seq.Name == "" {
+		return fmt.Errorf("sequence.name is required")
+	}
+	if seq.Step < 1 {
+		return fmt.Errorf("sequence.step must be at least 1, got %d", seq.Step)
+	}
+	if seq.Header == "" {
+		seq.Header = defaultSequenceHeader
+	}
+	return nil
+}
+
+// sequenceIdleTTL is how long a session's progress outlives its last step;
+// a session idle for longer starts its flow over.
+const sequenceIdleTTL = 24 * time.Hour
+
+// sequenceTracker remembers the last step completed by every session of every
+// flow. It is part of routerState so the steps of a flow, served by
+// different routes, see the same progress.
+type sequenceTracker struct {
+	mu       sync.Mutex
+	progress *idleMap[sequenceSession, int]
+}
+
+type sequenceSession struct {
+	flow, session string
+}
+
+// advance records step for the session if it is the next one expected and
+// returns the last step completed before it.
+func (t *sequenceTracker) advance(flow, session string, step int) (last int, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.progress == nil {
+		t.progress = newIdleMap[sequenceSession, int](sequenceIdleTTL)
+	}
+	now := time.Now()
+	key := sequenceSession{flow: flow, session: session}
+	last, _ = t.progress.get(key, now)
+	if step != 1 && step != last+1 {
+		return last, false
+	}
+	t.progress.set(key, step, now)
+	return last, true
+}
+
+// checkSequence answers 400 for a request without a session header and 409
+// for a step called out of order, returning false; otherwise it records the
+// step and returns true.
+func (h *routeHandler) checkSequence(w http.ResponseWriter, r *http.Request) (bool, error) {
+	seq := h.route.Sequence
+	session := r.Header.Get(seq.Header)
+	if session == "" {
+		return false, respondWithJSON(w, http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("missing %s header identifying the %s session", seq.Header, seq.Name),
+		})
+	}
+	if last, ok := h.state.sequences.advance(seq.Name, session, seq.Step); !ok {
+		return false, respondWithJSON(w, http.StatusConflict, map[string]any{
+			"error":    fmt.Sprintf("%s: step %d called out of order", seq.Name, seq.Step),
+			"expected": last + 1,
+			"got":      seq.Step,
+		})
+	}
+	return true, nil
+}
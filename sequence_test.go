@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const sequenceConfig = `{"routes": [
+	{"method": "POST", "path": "/cart", "sequence": {"name": "checkout", "step": 1}, "response": {"status": 200, "body": "cart"}},
+	{"method": "POST", "path": "/checkout", "sequence": {"name": "checkout", "step": 2}, "response": {"status": 200, "body": "checkout"}},
+	{"method": "POST", "path": "/pay", "sequence": {"name": "checkout", "step": 3}, "response": {"status": 200, "body": "pay"}}
+]}`
+
+func TestSequence(t *testing.T) {
+	srv := newTestServer(t, sequenceConfig)
+
+	step := func(session, path string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, nil)
+		if session != "" {
+			req.Header.Set("X-Session-Id", session)
+		}
+		res, _ := do(t, req)
+		return res.StatusCode
+	}
+
+	for _, path := range []string{"/cart", "/checkout", "/pay"} {
+		if status := step("a", path); status != 200 {
+			t.Errorf("in order %s: status = %d, want 200", path, status)
+		}
+	}
+
+	if status := step("b", "/checkout"); status != 409 {
+		t.Errorf("step 2 first: status = %d, want 409", status)
+	}
+	step("b", "/cart")
+	if status := step("b", "/pay"); status != 409 {
+		t.Errorf("step 3 after 1: status = %d, want 409", status)
+	}
+	// Sessions are independent: b's progress does not affect c.
+	if status := step("c", "/pay"); status != 409 {
+		t.Errorf("session c step 3: status = %d, want 409", status)
+	}
+	if status := step("", "/cart"); status != 400 {
+		t.Errorf("no session header: status = %d, want 400", status)
+	}
+}
+
+func TestValidateSequence(t *testing.T) {
+	for _, config := range []string{
+		`{"routes": [{"method": "GET", "path": "/a", "sequence": {"step": 1}, "response": {"status": 200}}]}`,
+		`{"routes": [{"method": "GET", "path": "/a", "sequence": {"name": "flow", "step": 0}, "response": {"status": 200}}]}`,
+	} {
+		if _, err := BuildRouter(parseInput(t, config)); err == nil || !strings.Contains(err.Error(), "sequence") {
+			t.Errorf("%s: err = %v, want a sequence error", config, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// signalContext returns a context cancelled when the process receives
+// SIGINT or SIGTERM.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// runServer serves on ln until ctx is cancelled, then shuts the server down
+// gracefully: in-flight requests get up to shutdownTimeout to finish, after
+// which the remaining connections are closed forcibly.
+//
+// When certFile and keyFile are set the listener is served over TLS.
+func runServer(ctx context.Context, srv *http.Server, ln net.Listener, certFile, keyFile string, shutdownTimeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if certFile != "" || keyFile != "" {
+			errCh <- srv.ServeTLS(ln, certFile, keyFile)
+		} else {
+			errCh <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Printf("⚠️ Shutdown timed out after %s, closing the remaining connections\n", shutdownTimeout)
+		return srv.Close()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("✅ Server shut down cleanly")
+	return nil
+}
+
+// serverTimeouts holds the -read-timeout, -write-timeout and -idle-timeout
+// settings; zero disables a timeout.
+type serverTimeouts struct {
+	read  time.Duration
+	write time.Duration
+	idle  time.Duration
+}
+
+// newHTTPServer returns a server for handler with the given timeouts, so a
+// stuck or slow client cannot hold a connection forever.
+func newHTTPServer(handler http.Handler, timeouts serverTimeouts, maxHeaderBytes int) *http.Server {
+	return &http.Server{
+		Handler:        handler,
+		ReadTimeout:    timeouts.read,
+		WriteTimeout:   timeouts.write,
+		IdleTimeout:    timeouts.idle,
+		MaxHeaderBytes: maxHeaderBytes,
+	}
+}
+
+// listen binds the TCP listener for the server, optionally with SO_REUSEPORT
+// so several Mocker processes can share the port across restarts.
+func listen(addr string, reusePort bool) (net.Listener, error) {
+	if !reusePort {
+		return net.Listen("tcp", addr)
+	}
+	if !reusePortSupported {
+		return nil, errors.New("-reuse-port is only supported on Linux, macOS and other Unix-like systems")
+	}
+	lc := net.ListenConfig{Control: setReusePort}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+// listenUnix binds a Unix domain socket at path for -listen-unix. A stale
+// socket file left by a crashed instance is replaced, but not one another
+// instance is still serving on. Closing the listener removes the file.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// listenPort returns the port a listener is actually bound to, which differs
+// from the configured one when port "0" is used.
+func listenPort(ln net.Listener) string {
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+		return strconv.Itoa(addr.Port)
+	}
+	return ln.Addr().String()
+}
+
+// readyInfo is the content of the file written by -ready-file.
+//
+// Example:
+//
+//	{
+//	  "address": "[::]:54321",
+//	  "port": "54321",
+//	  "pid": 4242,
+//	  "routes": [ { "method": "GET", "path": "/api/users" } ]
+//	}
+type readyInfo struct {
+	Address string       `json:"address"` // Address the server is bound to
+	Port    string       `json:"port"`    // Bound port, handy when port "0" was configured
+	PID     int          `json:"pid"`     // Process ID of the running Mocker
+	Routes  []readyRoute `json:"routes"`  // Routes being served
+}
+
+// readyRoute is a single method+path entry in readyInfo.
+type readyRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// writeReadyFile writes startup information for test harnesses to path.
+//
+// The file is written to a temporary name and renamed into place so readers
+// polling for it never observe a partially written file.
+func writeReadyFile(path string, addr net.Addr, input inputType) error {
+	info := readyInfo{
+		Address: addr.String(),
+		PID:     os.Getpid(),
+		Routes:  make([]readyRoute, 0, len(input.Routes)),
+	}
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		info.Port = strconv.Itoa(tcp.Port)
+	}
+	for _, route := range input.Routes {
+		info.Routes = append(info.Routes, readyRoute{Method: normalizeMethod(route.Method), Path: input.fullPath(route.Path)})
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWriteReadyFile(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	input := parseInput(t, `{"basePath": "/api", "routes": [
+		{"method": "get", "path": "/users"},
+		{"method": "POST", "path": "/users"}
+	]}`)
+	path := filepath.Join(t.TempDir(), "ready.json")
+	if err := writeReadyFile(path, ln.Addr(), input); err != nil {
+		t.Fatalf("writeReadyFile: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info readyInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatalf("ready file is not JSON: %v", err)
+	}
+	if info.Address != ln.Addr().String() {
+		t.Errorf("address = %q, want %q", info.Address, ln.Addr())
+	}
+	if want := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port); info.Port != want {
+		t.Errorf("port = %q, want %q", info.Port, want)
+	}
+	if info.PID != os.Getpid() {
+		t.Errorf("pid = %d, want %d", info.PID, os.Getpid())
+	}
+	want := []readyRoute{{Method: "GET", Path: "/api/users"}, {Method: "POST", Path: "/api/users"}}
+	if !reflect.DeepEqual(info.Routes, want) {
+		t.Errorf("routes = %v, want %v", info.Routes, want)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestServerReadTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := BuildRouter(parseInput(t, `{"routes": [{"method": "GET", "path": "/", "response": {"status": 200}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newHTTPServer(handler, serverTimeouts{read: 100 * time.Millisecond}, 0)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// A slow client sends part of the headers and then stalls.
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	conn.SetReadDeadline(start.Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("connection was not closed by the server: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("connection closed after %s, want about the 100ms read timeout", elapsed)
+	}
+}
+
+func TestRunServerShutdownTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release // far longer than the shutdown timeout
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runServer(ctx, srv, ln, "", "", 100*time.Millisecond) }()
+
+	reqErr := make(chan error, 1)
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err == nil {
+			res.Body.Close()
+		}
+		reqErr <- err
+	}()
+	<-started
+
+	start := time.Now()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runServer: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runServer did not return after the shutdown timeout")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("runServer returned after %s, before the shutdown timeout", elapsed)
+	}
+	if err := <-reqErr; err == nil {
+		t.Error("in-flight request succeeded, want its connection closed forcibly")
+	}
+}
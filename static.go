@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// mountStatic serves every directory in static (URL prefix -> local
+// directory) below its prefix. Mocked routes take precedence: chi prefers
+// static and param segments over the catch-all used here.
+//
+// Files are read from disk on every request, so rebuilding the directory
+// (e.g. an SPA build) needs no reload.
+func mountStatic(router chi.Router, static map[string]string) error {
+	for _, prefix := range slices.Sorted(maps.Keys(static)) {
+		dir := static[prefix]
+		if err := checkStaticDir(prefix, dir); err != nil {
+			return err
+		}
+		prefix = "/" + strings.Trim(prefix, "/")
+		files := http.FileServer(http.Dir(dir))
+		if prefix == "/" {
+			router.Handle("/*", files)
+		} else {
+			router.Handle(prefix+"/*", http.StripPrefix(prefix, files))
+		}
+		fmt.Printf("static %v -> %v\n", prefix, dir)
+	}
+	return nil
+}
+
+// checkStaticDir reports a static entry whose directory is missing.
+func checkStaticDir(prefix, dir string) error {
+	if !strings.HasPrefix(prefix, "/") {
+		return fmt.Errorf("static %q: prefix must start with /", prefix)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("static %q: %w", prefix, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("static %q: %s is not a directory", prefix, dir)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatic(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "index.html", "<h1>app</h1>")
+	writeConfig(t, dir, "app.js", "console.log(1)")
+	srv := newTestServer(t, `{"static": {"/app": "`+filepath.ToSlash(dir)+`"}, "routes": [
+		{"method": "GET", "path": "/api/users", "response": {"status": 200, "body": ["ada"]}},
+		{"method": "GET", "path": "/app/config.json", "response": {"status": 200, "body": {"mocked": true}}}
+	]}`)
+
+	for _, tt := range []struct {
+		path, body string
+		status     int
+	}{
+		{"/app/app.js", "console.log(1)", http.StatusOK},
+		{"/app/", "<h1>app</h1>", http.StatusOK},
+		{"/app/missing.js", "404 page not found\n", http.StatusNotFound},
+		{"/api/users", `["ada"]`, http.StatusOK},
+		{"/app/config.json", `{"mocked":true}`, http.StatusOK}, // mocks take precedence
+	} {
+		res, body := get(t, srv.URL+tt.path)
+		if res.StatusCode != tt.status || body != tt.body {
+			t.Errorf("%s: %d %q, want %d %q", tt.path, res.StatusCode, body, tt.status, tt.body)
+		}
+	}
+}
+
+func TestStaticErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := writeConfig(t, dir, "file.txt", "x")
+	for _, tt := range []struct {
+		prefix, dir, want string
+	}{
+		{"app", dir, "must start with /"},
+		{"/app", filepath.Join(dir, "missing"), "/app"},
+		{"/app", file, "is not a directory"},
+	} {
+		_, err := BuildRouter(parseInput(t, `{"static": {"`+tt.prefix+`": "`+filepath.ToSlash(tt.dir)+`"}, "routes": []}`))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("static %q: %q: err = %v, want %q", tt.prefix, tt.dir, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// statusFromType picks the response status from a field of the JSON request
+// body, e.g. for webhook mocks that should fail on demand.
+//
+// Example JSON fragment ({"simulate": "fail"} gets a 500, anything else 200):
+//
+//	"statusFrom": {
+//	  "field": "simulate",
+//	  "values": { "fail": 500, "timeout": 504 },
+//	  "default": 200
+//	}
+type statusFromType struct {
+	Field   string         `json:"field"`   // JSONPath of the field in the request body
+	Values  map[string]int `json:"values"`  // Field value -> status code
+	Default int            `json:"default"` // Status when the value is missing or unmapped (default: the response status)
+}
+
+// validateStatusFrom checks a statusFrom block before serving.
+func validateStatusFrom(s *statusFromType) error {
+	if s.Field == "" {
+		return fmt.Errorf("statusFrom.field is required")
+	}
+	if _, err := parseJSONPath(s.Field); err != nil {
+		return fmt.Errorf("statusFrom.field: %w", err)
+	}
+	for value, code := range s.Values {
+		if code < 100 || code > 999 {
+			return fmt.Errorf("statusFrom.values[%q]: invalid status %d", value, code)
+		}
+	}
+	return nil
+}
+
+// status returns the status code for the request, falling back to Default
+// and then to fallback. Non-string field values are compared in their JSON
+// form, so {"code": 7} matches the key "7".
+func (s *statusFromType) status(r *http.Request, fallback int) (int, error) {
+	if s.Default != 0 {
+		fallback = s.Default
+	}
+	raw, err := readRequestBody(r)
+	if err != nil {
+		return 0, err
+	}
+	var body any
+	if json.Unmarshal(raw, &body) != nil {
+		return fallback, nil
+	}
+	value, err := evalJSONPath(body, s.Field)
+	if err != nil || value == nil {
+		return fallback, err
+	}
+
+	key, ok := value.(string)
+	if !ok {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return 0, err
+		}
+		key = string(encoded)
+	}
+	if code, ok := s.Values[key]; ok {
+		return code, nil
+	}
+	return fallback, nil
+}
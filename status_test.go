@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestStatusFrom(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "POST", "path": "/webhook", "response": {"status": 200, "body": "received",
+		"statusFrom": {"field": "$.simulate", "values": {"fail": 500, "timeout": 504, "7": 422}, "default": 202}}}]}`)
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"simulate": "fail"}`, 500},
+		{`{"simulate": "timeout"}`, 504},
+		{`{"simulate": 7}`, 422},
+		{`{"simulate": "other"}`, 202},
+		{`{}`, 202},
+		{`not json`, 202},
+	}
+	for _, tt := range tests {
+		res, body := post(t, srv.URL+"/webhook", tt.body)
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.body, res.StatusCode, tt.status)
+		}
+		if body != `"received"` {
+			t.Errorf("%s: body = %s, want the configured body", tt.body, body)
+		}
+	}
+}
+
+func TestValidateStatusFrom(t *testing.T) {
+	for _, s := range []statusFromType{
+		{},
+		{Field: "items[x]"},
+		{Field: "simulate", Values: map[string]int{"fail": 42}},
+	} {
+		if err := validateStatusFrom(&s); err == nil {
+			t.Errorf("validateStatusFrom(%+v) accepted an invalid block", s)
+		}
+	}
+}
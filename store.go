@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// storeType links a route to an in-memory collection so mocks can be
+// stateful: a "create" route saves the posted object under a generated id and
+// a "get" route returns it again.
+//
+// Example JSON fragments:
+//
+//	{ "method": "POST", "path": "/api/orders",
+//	  "store": { "collection": "orders", "action": "create" },
+//	  "response": { "status": 201 } }
+//	{ "method": "GET", "path": "/api/orders/{id}",
+//	  "store": { "collection": "orders", "action": "get" },
+//	  "response": { "status": 200 } }
+type storeType struct {
+	Collection string `json:"collection"` // Name of the collection shared between routes
+	Action     string `json:"action"`     // "create" or "get"
+	IDField    string `json:"idField"`    // Body field holding the id (default: "id")
+	IDParam    string `json:"idParam"`    // Path param read by "get" (default: "id")
+}
+
+// memoryStore holds the collections of every store-backed route. One is
+// created per router, so state lives as long as the server.
+type memoryStore struct {
+	mu          sync.Mutex
+	collections map[string]map[string]any
+	lastID      map[string]int
+	values      map[string]map[string]any // named value stores filled by capture
+}
+
+// newMemoryStore returns an empty store.
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		collections: map[string]map[string]any{},
+		lastID:      map[string]int{},
+		values:      map[string]map[string]any{},
+	}
+}
+
+// setValue saves a captured value under key in the named value store.
+func (s *memoryStore) setValue(store, key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values[store] == nil {
+		s.values[store] = map[string]any{}
+	}
+	s.values[store][key] = value
+}
+
+// value returns a captured value, or nil if it was never captured.
+func (s *memoryStore) value(store, key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[store][key]
+}
+
+// create saves item in collection. An id already present in the item under
+// idField is kept; otherwise the next sequential id is assigned.
+func (s *memoryStore) create(collection, idField string, item map[string]any) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := item[idField]
+	if !ok || id == nil {
+		s.lastID[collection]++
+		id = strconv.Itoa(s.lastID[collection])
+		item[idField] = id
+	}
+	if s.collections[collection] == nil {
+		s.collections[collection] = map[string]any{}
+	}
+	s.collections[collection][fmt.Sprint(id)] = item
+	return item
+}
+
+// get returns the item stored under id in collection.
+func (s *memoryStore) get(collection, id string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.collections[collection][id]
+	return item, ok
+}
+
+// validateStore checks a route's store block at startup.
+func validateStore(st *storeType) error {
+	if st.Collection == "" {
+		return fmt.Errorf("store.collection is required")
+	}
+	if st.Action != "create" && st.Action != "get" {
+		return fmt.Errorf("store.action must be \"create\" or \"get\", got %q", st.Action)
+	}
+	return nil
+}
+
+// serveStore handles a store-backed route.
+//
+// For "create" the stored item is the configured response body (if it is an
+// object) overlaid with the posted JSON object, plus the id; it is returned as
+// the response body. For "get" the stored item is returned, or 404 if the id
+// is unknown.
+func (h *routeHandler) serveStore(w http.ResponseWriter, r *http.Request, res preparedResponse) error {
+	st := h.route.Store
+	idField := st.IDField
+	if idField == "" {
+		idField = "id"
+	}
+
+	if st.Action == "get" {
+		idParam := st.IDParam
+		if idParam == "" {
+			idParam = "id"
+		}
+		item, ok := h.state.store.get(st.Collection, chi.URLParam(r, idParam))
+		if !ok {
+			return respondWithJSON(w, http.StatusNotFound, map[string]string{"error": st.Collection + " not found"})
+		}
+		return respondWithJSON(w, res.Status, item)
+	}
+
+	item := map[string]any{}
+	if defaults, ok := res.Body.(map[string]any); ok {
+		cloned, err := cloneJSON(defaults)
+		if err != nil {
+			return err
+		}
+		item = cloned.(map[string]any)
+	}
+
+	raw, err := readRequestBody(r)
+	if err != nil {
+		return err
+	}
+	if len(raw) > 0 {
+		var posted map[string]any
+		if err := json.Unmarshal(raw, &posted); err != nil {
+			return respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "request body must be a JSON object"})
+		}
+		for k, v := range posted {
+			item[k] = v
+		}
+	}
+	return respondWithJSON(w, res.Status, h.state.store.create(st.Collection, idField, item))
+}
+
+// captureType copies fields of the request body into a named value store so
+// later responses can read them with the state template function.
+//
+// Example JSON fragment (signup stores the email, profile reads it back with
+// {{state "signup" "email" | json}}):
+//
+//	"capture": {
+//	  "store": "signup",
+//	  "fields": { "email": "email", "city": "address.city" }
+//	}
+type captureType struct {
+	Store  string            `json:"store"`  // Name of the value store
+	Fields map[string]string `json:"fields"` // Key to save under -> JSONPath into the request body
+}
+
+// capture saves the configured request body fields. Fields missing from the
+// body are left untouched.
+func (h *routeHandler) capture(r *http.Request) error {
+	raw, err := readRequestBody(r)
+	if err != nil {
+		return err
+	}
+	var body any
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil // nothing to capture from a non-JSON body
+	}
+
+	c := h.route.Capture
+	for key, path := range c.Fields {
+		value, err := evalJSONPath(body, path)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			h.state.store.setValue(c.Store, key, value)
+		}
+	}
+	return nil
+}
+
+// readRequestBody reads the whole request body and puts an identical reader
+// back, so the body can be consumed again later.
+func readRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+	return raw, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStoreCreateAndGet(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "POST", "path": "/orders", "store": {"collection": "orders", "action": "create"},
+		 "response": {"status": 201, "body": {"state": "new"}}},
+		{"method": "GET", "path": "/orders/{id}", "store": {"collection": "orders", "action": "get"},
+		 "response": {"status": 200}}
+	]}`)
+
+	res, body := post(t, srv.URL+"/orders", `{"item": "book"}`)
+	if res.StatusCode != 201 {
+		t.Fatalf("create status = %d, want 201", res.StatusCode)
+	}
+	var created map[string]any
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatalf("create body %s: %v", body, err)
+	}
+	id, _ := created["id"].(string)
+	if id == "" {
+		t.Fatalf("create body %s has no id", body)
+	}
+
+	res, got := get(t, srv.URL+"/orders/"+id)
+	if res.StatusCode != 200 || got != body {
+		t.Errorf("get = %d %s, want 200 %s", res.StatusCode, got, body)
+	}
+	if res, _ := get(t, srv.URL+"/orders/999"); res.StatusCode != 404 {
+		t.Errorf("unknown id status = %d, want 404", res.StatusCode)
+	}
+}
+
+func TestStoreRejectsInvalidBody(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "POST", "path": "/orders", "store": {"collection": "orders", "action": "create"}, "response": {"status": 201}}
+	]}`)
+	if res, _ := post(t, srv.URL+"/orders", `[1, 2]`); res.StatusCode != 400 {
+		t.Errorf("status = %d, want 400", res.StatusCode)
+	}
+}
+
+func TestCaptureAndState(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "POST", "path": "/signup", "capture": {"store": "signup", "fields": {"email": "email", "city": "address.city"}},
+		 "response": {"status": 201}},
+		{"method": "GET", "path": "/profile", "response": {"status": 200,
+		 "bodyTemplate": "{\"email\": {{state \"signup\" \"email\" | json}}, \"city\": {{state \"signup\" \"city\" | json}}, \"phone\": {{state \"signup\" \"phone\" | json}}}"}}
+	]}`)
+
+	if _, body := get(t, srv.URL+"/profile"); body != `{"email": null, "city": null, "phone": null}` {
+		t.Errorf("before signup: body = %s", body)
+	}
+	post(t, srv.URL+"/signup", `{"email": "ada@example.com", "address": {"city": "London"}}`)
+	if _, body := get(t, srv.URL+"/profile"); body != `{"email": "ada@example.com", "city": "London", "phone": null}` {
+		t.Errorf("after signup: body = %s", body)
+	}
+}
+
+func TestCaptureInvalidPath(t *testing.T) {
+	input := parseInput(t, `{"routes": [{"method": "POST", "path": "/signup",
+		"capture": {"store": "signup", "fields": {"city": "address..city"}}, "response": {"status": 201}}]}`)
+	if _, err := BuildRouter(input); err == nil || !strings.Contains(err.Error(), "capture.fields.city") {
+		t.Errorf("err = %v, want an invalid capture.fields.city error", err)
+	}
+}
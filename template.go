@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// templateData is the context a response bodyTemplate is executed with.
+//
+// Example template:
+//
+//	{"sku": {{jsonpath "order.items[0].sku" | json}}, "user": "{{.Params.id}}"}
+type templateData struct {
+	Method  string            // Request method
+	Path    string            // Request path
+	Params  map[string]string // Path params, e.g. {id} in /api/users/{id}
+	Query   map[string]string // First value of every query param
+	Headers map[string]string // First value of every request header
+	Body    any               // Request body decoded as JSON; nil if empty or not JSON
+
+	state   *routerState  // shared router state backing functions like nextId
+	request *http.Request // the request, for functions like hasQuery
+}
+
+// newTemplateData builds the template context for a request.
+//
+// The request body is read and then restored so later handlers can still
+// consume it.
+func newTemplateData(r *http.Request, state *routerState) (*templateData, error) {
+	data := &templateData{
+		state:   state,
+		request: r,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Params:  map[string]string{},
+		Query:   map[string]string{},
+		Headers: map[string]string{},
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, key := range rctx.URLParams.Keys {
+			data.Params[key] = rctx.URLParams.Values[i]
+		}
+	}
+	for key, values := range r.URL.Query() {
+		data.Query[key] = values[0]
+	}
+	for key, values := range r.Header {
+		data.Headers[key] = values[0]
+	}
+
+	raw, err := readRequestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &data.Body) // non-JSON bodies are left nil
+	}
+	return data, nil
+}
+
+// parseBodyTemplate parses a response bodyTemplate with Mocker's functions.
+//
+// Functions that need the request (like jsonpath) are bound per request in
+// renderTemplate; the placeholders here only make parsing succeed.
+func parseBodyTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs(nil)).Parse(text)
+}
+
+// templateFuncs returns the functions available in body templates:
+//
+//   - jsonpath "expr": value at a JSONPath expression (e.g. "$.order.items[0].sku")
+//     in the request body, or nil when it does not exist
+//   - json: encodes a value as JSON, e.g. {{jsonpath "user" | json}}
+//   - default value x: x, or value when x is nil or "", e.g.
+//     {{jsonpath "id" | default 0 | json}}
+//   - nextId: a server-wide counter starting at 1, incremented on every call
+//     (reset by restarting Mocker)
+//   - now: the current UTC time in RFC 3339 format
+//   - uuid: a random version 4 UUID, different on every call
+//   - env "VAR": the environment variable's value, read on every request so
+//     changes made while Mocker runs are picked up ("" when unset)
+//   - state "store" "key": a value saved by a route's capture block, or nil
+//   - seq start end: the integers start..end (inclusive) for range loops;
+//     use the loop index to place commas between array elements:
+//     [{{range $i, $n := seq 1 50}}{{if $i}},{{end}}{"id": {{$n}}}{{end}}]
+//   - hasQuery "name" / hasHeader "name": whether the request has the query
+//     param or header, even with an empty value, for conditional fields:
+//     {"ok": true{{if hasQuery "fail"}}, "error": "forced"{{end}}}
+//   - queryInt "name" default max: the query param as an integer, default when
+//     missing or not a number, clamped to 0..max; with seq it sizes arrays
+//     from the request, e.g. /users?count=10:
+//     [{{range $i, $n := seq 1 (queryInt "count" 5 100)}}...{{end}}]
+func templateFuncs(data *templateData) template.FuncMap {
+	return template.FuncMap{
+		"seq":  seq,
+		"env":  os.Getenv,
+		"uuid": newUUID,
+		"now": func() string {
+			return time.Now().UTC().Format(time.RFC3339)
+		},
+		"state": func(store, key string) any {
+			if data == nil {
+				return nil
+			}
+			return data.state.store.value(store, key)
+		},
+		"nextId": func() int64 {
+			if data == nil {
+				return 0
+			}
+			return data.state.nextID.Add(1)
+		},
+		"hasQuery": func(name string) bool {
+			return data != nil && data.request.URL.Query().Has(name)
+		},
+		"hasHeader": func(name string) bool {
+			if data == nil {
+				return false
+			}
+			_, ok := data.request.Header[http.CanonicalHeaderKey(name)]
+			return ok
+		},
+		"queryInt": func(name string, def, limit int) int {
+			if data == nil {
+				return def
+			}
+			return queryInt(data.Query[name], def, limit)
+		},
+		"jsonpath": func(expr string) (any, error) {
+			if data == nil {
+				return nil, nil
+			}
+			return evalJSONPath(data.Body, expr)
+		},
+		"default": func(def, v any) any {
+			if v == nil || v == "" {
+				return def
+			}
+			return v
+		},
+		"json": func(v any) (string, error) {
+			out, err := json.Marshal(v)
+			return string(out), err
+		},
+	}
+}
+
+// newUUID returns a random version 4 UUID. Its bytes come from rng, so -seed
+// makes it repeat across runs.
+func newUUID() string {
+	var b [16]byte
+	randRead(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// parseHeaderTemplates parses the header values that contain template
+// actions, e.g. "X-Request-Id": "{{uuid}}". Plain values are left out.
+func parseHeaderTemplates(headers map[string]string) (map[string]*template.Template, error) {
+	var out map[string]*template.Template
+	for name, value := range headers {
+		if !strings.Contains(value, "{{") {
+			continue
+		}
+		tmpl, err := parseBodyTemplate(name, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header %s: %w", name, err)
+		}
+		if out == nil {
+			out = map[string]*template.Template{}
+		}
+		out[name] = tmpl
+	}
+	return out, nil
+}
+
+// renderHeaders executes the header templates for the request. Unlike
+// bodies, the output is not required to be JSON.
+func renderHeaders(tmpls map[string]*template.Template, r *http.Request, state *routerState) (map[string]string, error) {
+	if len(tmpls) == 0 {
+		return nil, nil
+	}
+	data, err := newTemplateData(r, state)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(tmpls))
+	for name, tmpl := range tmpls {
+		value, err := executeTemplate(tmpl, data)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", name, err)
+		}
+		out[name] = string(value)
+	}
+	return out, nil
+}
+
+// maxSeqLen caps the length of seq so a template cannot exhaust memory.
+const maxSeqLen = 10000
+
+// seq returns the integers from start to end inclusive (empty if end < start).
+func seq(start, end int) ([]int, error) {
+	if end < start {
+		return nil, nil
+	}
+	if end-start+1 > maxSeqLen {
+		return nil, fmt.Errorf("seq %d %d: more than %d items", start, end, maxSeqLen)
+	}
+	out := make([]int, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		out = append(out, i)
+	}
+	return out, nil
+}
+
+// queryInt parses a query param value as an integer, falling back to def,
+// and clamps the result to 0..limit.
+func queryInt(value string, def, limit int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		n = def
+	}
+	return min(max(n, 0), limit)
+}
+
+// renderTemplate executes tmpl for the request and checks the output is
+// valid JSON, since it is served as application/json.
+func renderTemplate(tmpl *template.Template, r *http.Request, state *routerState) ([]byte, error) {
+	data, err := newTemplateData(r, state)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := executeTemplate(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(out) {
+		return nil, fmt.Errorf("template %q did not render valid JSON", tmpl.Name())
+	}
+	return out, nil
+}
+
+// executeTemplate runs a parsed template with the functions bound to data.
+func executeTemplate(tmpl *template.Template, data *templateData) ([]byte, error) {
+	t, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Funcs(templateFuncs(data)).Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// evalJSONPath evaluates a simple JSONPath expression against a decoded JSON
+// value. Supported syntax is an optional leading "$", dot-separated field
+// names, bracketed indexes ([0]) and bracketed quoted keys (['a.b']).
+//
+// A syntactically invalid expression returns an error; a path that does not
+// exist in v returns nil.
+func evalJSONPath(v any, expr string) (any, error) {
+	segments, err := parseJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	cur := v
+	for _, seg := range segments {
+		switch key := seg.(type) {
+		case string:
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil, nil
+			}
+			if cur, ok = obj[key]; !ok {
+				return nil, nil
+			}
+		case int:
+			arr, ok := cur.([]any)
+			if !ok || key < 0 || key >= len(arr) {
+				return nil, nil
+			}
+			cur = arr[key]
+		}
+	}
+	return cur, nil
+}
+
+// parseJSONPath splits a JSONPath expression into field names (string) and
+// array indexes (int).
+func parseJSONPath(expr string) ([]any, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid JSONPath %q: %s", expr, reason)
+	}
+
+	s := strings.TrimPrefix(strings.TrimSpace(expr), "$")
+	s = strings.TrimPrefix(s, ".")
+	var segments []any
+	for s != "" {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			if s == "" || s[0] == '.' || s[0] == '[' {
+				return nil, invalid("empty field name")
+			}
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, invalid("missing ]")
+			}
+			inner := s[1:end]
+			s = s[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, inner[1:len(inner)-1])
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, invalid("index must be an integer or a quoted key")
+			}
+			segments = append(segments, i)
+		default:
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			segments = append(segments, s[:end])
+			s = s[end:]
+		}
+	}
+	if len(segments) == 0 && strings.TrimSpace(expr) == "" {
+		return nil, errors.New("empty JSONPath expression")
+	}
+	return segments, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// post sends a POST request with a JSON body to url.
+func post(t *testing.T, url, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return do(t, req)
+}
+
+func TestTemplateJSONPath(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "POST", "path": "/orders", "response": {"status": 201,
+		 "bodyTemplate": "{\"sku\": {{jsonpath \"$.order.items[1].sku\" | json}}, \"missing\": {{jsonpath \"order.nope\" | default 0 | json}}}"}},
+		{"method": "POST", "path": "/broken", "response": {"status": 200,
+		 "bodyTemplate": "{\"sku\": {{jsonpath \"order.items[x]\" | json}}}"}}
+	]}`)
+
+	res, body := post(t, srv.URL+"/orders", `{"order": {"items": [{"sku": "A-1"}, {"sku": "B-2"}]}}`)
+	if res.StatusCode != 201 {
+		t.Errorf("status = %d, want 201", res.StatusCode)
+	}
+	if want := `{"sku": "B-2", "missing": 0}`; body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+
+	if res, _ := post(t, srv.URL+"/broken", `{}`); res.StatusCode != 500 {
+		t.Errorf("invalid expression: status = %d, want 500", res.StatusCode)
+	}
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	doc := map[string]any{
+		"order": map[string]any{"items": []any{map[string]any{"sku": "A-1"}}},
+		"a.b":   "dotted",
+	}
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"$.order.items[0].sku", "A-1"},
+		{"order.items[0].sku", "A-1"},
+		{"['a.b']", "dotted"},
+		{"order.items[5].sku", nil},
+		{"order.missing", nil},
+	}
+	for _, tt := range tests {
+		got, err := evalJSONPath(doc, tt.expr)
+		if err != nil || got != tt.want {
+			t.Errorf("evalJSONPath(%q) = %v, %v; want %v", tt.expr, got, err, tt.want)
+		}
+	}
+	for _, expr := range []string{"order.items[x]", "order.items[0", "['unterminated]"} {
+		if _, err := evalJSONPath(doc, expr); err == nil {
+			t.Errorf("evalJSONPath(%q) accepted an invalid expression", expr)
+		}
+	}
+}
+
+func TestTemplateSeq(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/users", "response": {"status": 200,
+		"bodyTemplate": "[{{range $i, $n := seq 1 50}}{{if $i}},{{end}}{\"id\": {{$n}}, \"name\": \"user{{$n}}\"}{{end}}]"}}]}`)
+
+	_, body := get(t, srv.URL+"/users")
+	var users []map[string]any
+	if err := json.Unmarshal([]byte(body), &users); err != nil {
+		t.Fatalf("body is not a JSON array: %v\n%s", err, body)
+	}
+	if len(users) != 50 {
+		t.Fatalf("got %d users, want 50", len(users))
+	}
+	for i, u := range users {
+		if u["id"] != float64(i+1) || u["name"] != fmt.Sprintf("user%d", i+1) || len(u) != 2 {
+			t.Errorf("users[%d] = %v", i, u)
+		}
+	}
+}
+
+func TestSeq(t *testing.T) {
+	if got, _ := seq(3, 5); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Errorf("seq(3, 5) = %v, want [3 4 5]", got)
+	}
+	if got, _ := seq(5, 3); len(got) != 0 {
+		t.Errorf("seq(5, 3) = %v, want []", got)
+	}
+	if _, err := seq(1, maxSeqLen+1); err == nil {
+		t.Errorf("seq(1, %d) did not fail", maxSeqLen+1)
+	}
+}
+
+func TestTemplateNextID(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "POST", "path": "/users", "response": {"status": 201, "bodyTemplate": "{\"id\": {{nextId}}}"}},
+		{"method": "POST", "path": "/orders", "response": {"status": 201, "bodyTemplate": "{\"id\": {{nextId}}}"}}
+	]}`)
+
+	for i, path := range []string{"/users", "/users", "/orders"} {
+		want := fmt.Sprintf(`{"id": %d}`, i+1)
+		if _, body := post(t, srv.URL+path, `{}`); body != want {
+			t.Errorf("call %d (%s): body = %s, want %s", i+1, path, body, want)
+		}
+	}
+
+	// A new router (as on restart or reload) starts counting again.
+	srv = newTestServer(t, `{"routes": [{"method": "POST", "path": "/users", "response": {"status": 201, "bodyTemplate": "{\"id\": {{nextId}}}"}}]}`)
+	if _, body := post(t, srv.URL+"/users", `{}`); body != `{"id": 1}` {
+		t.Errorf("after restart: body = %s, want {\"id\": 1}", body)
+	}
+}
+
+func TestTemplateEnv(t *testing.T) {
+	t.Setenv("MOCKER_TEST_REGION", "eu-west-1")
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/region", "response": {"status": 200,
+		"bodyTemplate": "{\"region\": {{env \"MOCKER_TEST_REGION\" | json}}, \"unset\": {{env \"MOCKER_TEST_UNSET\" | json}}}"}}]}`)
+
+	if _, body := get(t, srv.URL+"/region"); body != `{"region": "eu-west-1", "unset": ""}` {
+		t.Errorf("body = %s", body)
+	}
+	os.Setenv("MOCKER_TEST_REGION", "us-east-2") // restored by t.Setenv
+	if _, body := get(t, srv.URL+"/region"); body != `{"region": "us-east-2", "unset": ""}` {
+		t.Errorf("after changing the variable: body = %s", body)
+	}
+}
+
+func TestBodyTemplateFile(t *testing.T) {
+	dir := t.TempDir()
+	file := writeConfig(t, dir, "greeting.tmpl", `{"greeting": "hello {{.Query.name}}"}`)
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/greet", "response": {"status": 200, "bodyTemplateFile": "`+filepath.ToSlash(file)+`"}}
+	]}`)
+
+	if _, body := get(t, srv.URL+"/greet?name=Ada"); body != `{"greeting": "hello Ada"}` {
+		t.Errorf("body = %s", body)
+	}
+
+	bad := writeConfig(t, dir, "bad.tmpl", `{{.Query.name`)
+	for file, want := range map[string]string{
+		bad:                             "invalid bodyTemplateFile",
+		filepath.Join(dir, "none.tmpl"): "invalid bodyTemplateFile",
+	} {
+		_, err := BuildRouter(parseInput(t, `{"routes": [{"method": "GET", "path": "/greet", "response": {"status": 200, "bodyTemplateFile": "`+filepath.ToSlash(file)+`"}}]}`))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", filepath.Base(file), err, want)
+		}
+	}
+}
+
+func TestTemplateQueryInt(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/users", "response": {"status": 200,
+		"bodyTemplate": "[{{range $i, $n := seq 1 (queryInt \"count\" 5 100)}}{{if $i}},{{end}}{\"id\": {{$n}}}{{end}}]"}}]}`)
+
+	for query, want := range map[string]int{
+		"?count=10":   10,
+		"?count=1":    1,
+		"?count=0":    0,
+		"":            5,   // default
+		"?count=ten":  5,   // not a number
+		"?count=-3":   0,   // clamped to 0
+		"?count=1000": 100, // clamped to the max
+	} {
+		_, body := get(t, srv.URL+"/users"+query)
+		var users []map[string]any
+		if err := json.Unmarshal([]byte(body), &users); err != nil {
+			t.Fatalf("%s: body is not a JSON array: %v\n%s", query, err, body)
+		}
+		if len(users) != want {
+			t.Errorf("%q: got %d users, want %d", query, len(users), want)
+		}
+	}
+}
+
+func TestTemplateConditionals(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/orders", "response": {"status": 200,
+		"bodyTemplate": "{\"ok\": {{if hasQuery \"fail\"}}false, \"error\": \"forced failure{{with .Query.fail}}: {{.}}{{end}}\"{{else}}true{{end}}{{if hasHeader \"X-Debug\"}}, \"debug\": true{{end}}}"}}]}`)
+
+	for _, tt := range []struct {
+		query, debug string
+		want         string
+	}{
+		{"", "", `{"ok":true}`},
+		{"?fail", "", `{"error":"forced failure","ok":false}`},
+		{"?fail=timeout", "", `{"error":"forced failure: timeout","ok":false}`},
+		{"", "1", `{"debug":true,"ok":true}`},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/orders"+tt.query, nil)
+		if tt.debug != "" {
+			req.Header.Set("X-Debug", tt.debug)
+		}
+		_, body := do(t, req)
+		var v any
+		if err := json.Unmarshal([]byte(body), &v); err != nil {
+			t.Fatalf("%q: body is not JSON: %v\n%s", tt.query, err, body)
+		}
+		if got, _ := json.Marshal(v); string(got) != tt.want {
+			t.Errorf("%q debug=%q: body = %s, want %s", tt.query, tt.debug, got, tt.want)
+		}
+	}
+}
+
+func TestHeaderTemplates(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [{"method": "GET", "path": "/api/users/{id}", "response": {
+		"status": 200,
+		"headers": {
+			"X-Request-Id": "{{uuid}}",
+			"X-Trace": "{{.Query.trace}}-{id}",
+			"Location": "/api/users/{id}"
+		}
+	}}]}`)
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		res, _ := get(t, srv.URL+"/api/users/7?trace=abc")
+		id := res.Header.Get("X-Request-Id")
+		if !uuidPattern.MatchString(id) {
+			t.Errorf("X-Request-Id = %q, want a version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Errorf("X-Request-Id %q repeated", id)
+		}
+		seen[id] = true
+		if got := res.Header.Get("X-Trace"); got != "abc-7" {
+			t.Errorf("X-Trace = %q, want abc-7", got)
+		}
+		if got := res.Header.Get("Location"); got != "/api/users/7" {
+			t.Errorf("Location = %q, want /api/users/7", got)
+		}
+	}
+
+	input := parseInput(t, `{"routes": [{"method": "GET", "path": "/a", "response": {"status": 200, "headers": {"X-Bad": "{{uuid"}}}]}`)
+	if _, err := BuildRouter(input); err == nil || !strings.Contains(err.Error(), "X-Bad") {
+		t.Errorf("invalid header template: err = %v, want one naming the header", err)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// buildTLSConfig returns the TLS configuration for the HTTPS server.
+//
+// When clientCAPath is set, Mocker runs in mutual TLS mode: every client must
+// present a certificate signed by one of the CAs in that PEM bundle, and the
+// handshake is rejected otherwise.
+func buildTLSConfig(clientCAPath string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientCAPath == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(clientCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid certificates found in %s", clientCAPath)
+	}
+
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	cfg.ClientCAs = pool
+	return cfg, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// traceEntry is one line of a -trace file: a served request and the
+// response it got, as sent on the wire (after compression, when enabled).
+//
+// Example line:
+//
+//	{"time":"2025-01-02T15:04:05Z","method":"GET","path":"/api/users/1","status":200,
+//	 "headers":{"Content-Type":"application/json"},"body":"{\"id\":1}"}
+type traceEntry struct {
+	Time       time.Time         `json:"time"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Query      string            `json:"query,omitempty"`
+	Status     int               `json:"status"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`       // Response body when it is valid UTF-8
+	BodyBase64 string            `json:"bodyBase64,omitempty"` // Response body otherwise
+}
+
+// traceLog appends trace entries to a writer shared by every router (and
+// every reload of it).
+type traceLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// newTraceLog returns a trace log writing JSON lines to w.
+func newTraceLog(w io.Writer) *traceLog {
+	return &traceLog{w: w}
+}
+
+// write appends one entry as a single line.
+func (t *traceLog) write(entry traceEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = t.w.Write(append(line, '\n'))
+}
+
+// traceHeaderSkip lists response headers not worth recording: they are
+// regenerated by whoever serves the trace.
+var traceHeaderSkip = map[string]bool{
+	"Content-Length": true,
+	"Date":           true,
+	"Server":         true,
+	"Traceparent":    true,
+}
+
+// recordTrace returns a middleware appending every request and its response
+// to t.
+func recordTrace(t *traceLog) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&body)
+			next.ServeHTTP(ww, r)
+
+			entry := traceEntry{
+				Time:    time.Now().UTC(),
+				Method:  r.Method,
+				Path:    r.URL.Path,
+				Query:   r.URL.RawQuery,
+				Status:  ww.Status(),
+				Headers: map[string]string{},
+			}
+			if entry.Status == 0 {
+				entry.Status = http.StatusOK
+			}
+			for name, values := range ww.Header() {
+				if !traceHeaderSkip[name] && len(values) > 0 {
+					entry.Headers[name] = values[0]
+				}
+			}
+			if utf8.Valid(body.Bytes()) {
+				entry.Body = body.String()
+			} else {
+				entry.BodyBase64 = base64.StdEncoding.EncodeToString(body.Bytes())
+			}
+			t.write(entry)
+		})
+	}
+}
+
+// loadReplayConfig turns a -trace file into a config serving the recorded
+// responses.
+//
+// Entries are grouped by method+path (the query is ignored); the calls to a
+// path get its recorded responses in order and the last one is repeated
+// once they run out.
+func loadReplayConfig(tracePath string) (inputType, error) {
+	var input inputType
+	f, err := os.Open(tracePath)
+	if err != nil {
+		return input, fmt.Errorf("error in reading the trace: %w", err)
+	}
+	defer f.Close()
+
+	routes := map[string]int{} // method+path -> index in input.Routes
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry traceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return input, fmt.Errorf("%s:%d: %w", tracePath, line, err)
+		}
+
+		res := response{Status: entry.Status, Headers: entry.Headers, ContentType: entry.Headers["Content-Type"]}
+		if entry.BodyBase64 != "" {
+			res.BodyBase64 = entry.BodyBase64
+		} else {
+			res.BodyBase64 = base64.StdEncoding.EncodeToString([]byte(entry.Body))
+		}
+
+		key := entry.Method + " " + entry.Path
+		i, ok := routes[key]
+		if !ok {
+			i = len(input.Routes)
+			routes[key] = i
+			input.Routes = append(input.Routes, routesType{Method: entry.Method, Path: entry.Path})
+		}
+		route := &input.Routes[i]
+		res.AfterCalls = len(route.Responses) // the n-th recorded response serves call n+1
+		route.Responses = append(route.Responses, res)
+	}
+	if err := scanner.Err(); err != nil {
+		return input, fmt.Errorf("error in reading the trace: %w", err)
+	}
+	if len(input.Routes) == 0 {
+		return input, fmt.Errorf("trace %s has no entries", tracePath)
+	}
+	input.Port = defaultPort
+	return input, nil
+}
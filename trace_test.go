@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplayTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	trace := strings.Join([]string{
+		`{"time":"2025-01-02T15:04:05Z","method":"GET","path":"/api/users/1","status":200,"headers":{"Content-Type":"application/json","X-Request-Id":"a"},"body":"{\"id\":1,\"v\":1}"}`,
+		`{"time":"2025-01-02T15:04:06Z","method":"GET","path":"/api/users/1","query":"fresh=1","status":200,"headers":{"Content-Type":"application/json"},"body":"{\"id\":1,\"v\":2}"}`,
+		``,
+		`{"time":"2025-01-02T15:04:07Z","method":"DELETE","path":"/api/users/1","status":204}`,
+		`{"time":"2025-01-02T15:04:08Z","method":"GET","path":"/logo.png","status":200,"headers":{"Content-Type":"image/png"},"bodyBase64":"iVBORw0K"}`,
+	}, "\n")
+	if err := os.WriteFile(path, []byte(trace), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	input, err := loadReplayConfig(path)
+	if err != nil {
+		t.Fatalf("loadReplayConfig: %v", err)
+	}
+	if input.Port != defaultPort || len(input.Routes) != 3 {
+		t.Fatalf("port %q, %d routes", input.Port, len(input.Routes))
+	}
+	srv := serveInput(t, input)
+
+	for i, tt := range []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{"GET", "/api/users/1", 200, `{"id":1,"v":1}`},
+		{"GET", "/api/users/1", 200, `{"id":1,"v":2}`},
+		{"GET", "/api/users/1?other=1", 200, `{"id":1,"v":2}`}, // the last one repeats
+		{"DELETE", "/api/users/1", 204, ""},
+		{"GET", "/logo.png", 200, "\x89PNG\r\n"},
+	} {
+		req, _ := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+		res, body := do(t, req)
+		if res.StatusCode != tt.status || body != tt.body {
+			t.Errorf("call %d, %s %s: %d %q, want %d %q", i+1, tt.method, tt.path, res.StatusCode, body, tt.status, tt.body)
+		}
+		if i == 0 && res.Header.Get("X-Request-Id") != "a" {
+			t.Errorf("recorded header not replayed: %v", res.Header)
+		}
+		if i == 4 && res.Header.Get("Content-Type") != "image/png" {
+			t.Errorf("Content-Type = %q, want image/png", res.Header.Get("Content-Type"))
+		}
+	}
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	out := &syncBuffer{}
+	input := parseInput(t, `{"routes": [{"method": "POST", "path": "/orders", "response": {"status": 201, "headers": {"Location": "/orders/1"}, "body": {"id": 1}}}]}`)
+	input.Trace = newTraceLog(out)
+	srv := serveInput(t, input)
+	post(t, srv.URL+"/orders?dry=1", `{}`)
+
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	if err := os.WriteFile(path, []byte(out.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := loadReplayConfig(path)
+	if err != nil {
+		t.Fatalf("loadReplayConfig: %v\n%s", err, out.String())
+	}
+	res, body := post(t, serveInput(t, replayed).URL+"/orders", `{}`)
+	if res.StatusCode != http.StatusCreated || body != `{"id":1}` || res.Header.Get("Location") != "/orders/1" {
+		t.Errorf("replayed: %d %s %v", res.StatusCode, body, res.Header)
+	}
+}
+
+func TestLoadReplayConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := writeConfig(t, dir, "empty.jsonl", "\n")
+	broken := writeConfig(t, dir, "broken.jsonl", `{"method":"GET","path":"/a","status":200}`+"\n{")
+	for path, want := range map[string]string{
+		filepath.Join(dir, "missing.jsonl"): "reading the trace",
+		empty:                               "has no entries",
+		broken:                              "broken.jsonl:2",
+	} {
+		if _, err := loadReplayConfig(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", filepath.Base(path), err, want)
+		}
+	}
+}
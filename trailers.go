@@ -0,0 +1,36 @@
+package main
+
+import "net/http"
+
+// declareTrailers announces the response trailers in the Trailer header,
+// which must happen before the header is written.
+func declareTrailers(w http.ResponseWriter, trailers map[string]string) {
+	for name := range trailers {
+		w.Header().Add("Trailer", name)
+	}
+}
+
+// setTrailers sets the trailer values once the body has been written;
+// net/http sends them after the last chunk.
+func setTrailers(w http.ResponseWriter, trailers map[string]string) {
+	for name, value := range trailers {
+		w.Header().Set(name, value)
+	}
+}
+
+// trailerWriter drops Content-Length so the body is sent chunked, which
+// HTTP/1.1 needs to carry trailers.
+type trailerWriter struct {
+	http.ResponseWriter
+}
+
+// WriteHeader removes Content-Length before writing the header.
+func (t *trailerWriter) WriteHeader(code int) {
+	t.Header().Del("Content-Length")
+	t.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (t *trailerWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
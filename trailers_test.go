@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestTrailers(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/stream", "response": {"status": 200, "body": {"ok": true},
+			"trailers": {"Grpc-Status": "0", "X-Checksum": "abc123"}}}
+	]}`)
+
+	res, body := get(t, srv.URL+"/stream")
+	if body != `{"ok":true}` {
+		t.Errorf("body = %s", body)
+	}
+	if res.ContentLength != -1 || len(res.TransferEncoding) == 0 || res.TransferEncoding[0] != "chunked" {
+		t.Errorf("response not chunked: Content-Length %d, Transfer-Encoding %v", res.ContentLength, res.TransferEncoding)
+	}
+	// The trailers are only known once the body has been read.
+	for name, want := range map[string]string{"Grpc-Status": "0", "X-Checksum": "abc123"} {
+		if got := res.Trailer.Get(name); got != want {
+			t.Errorf("trailer %s = %q, want %q", name, got, want)
+		}
+		if got := res.Header.Get(name); got != "" {
+			t.Errorf("trailer %s also sent as header: %q", name, got)
+		}
+	}
+}
+
+func TestTrailersEmptyBody(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/stream", "response": {"status": 200, "trailers": {"X-Checksum": "abc123"}}}
+	]}`)
+	res, _ := get(t, srv.URL+"/stream")
+	if got := res.Trailer.Get("X-Checksum"); got != "abc123" {
+		t.Errorf("trailer = %q, want abc123", got)
+	}
+}
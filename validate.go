@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// validMethods are the HTTP methods a route may use.
+var validMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE"}
+
+// normalizeMethod upper-cases a configured method so "get" and "GET" are the
+// same route.
+func normalizeMethod(method string) string {
+	return strings.ToUpper(strings.TrimSpace(method))
+}
+
+// validateRoutes checks the route definitions before anything is registered,
+// turning config mistakes into descriptive startup errors instead of panics
+// or silently shadowed routes deep inside the router.
+//
+// Routes may share method+path only as match variants: at most one of them
+// can go without a match block (the fallback).
+func validateRoutes(input inputType) error {
+	type seenRoute struct {
+		index    int    // 1-based number of the first route with this key
+		path     string // its full path
+		fallback int    // number of its route without a match block, 0 if none yet
+	}
+	seen := map[string]*seenRoute{}
+
+	for i, route := range input.Routes {
+		method := normalizeMethod(route.Method)
+		if !slices.Contains(validMethods, method) {
+			return fmt.Errorf("route #%d (%s): invalid method %q, must be one of %s",
+				i+1, route.Path, route.Method, strings.Join(validMethods, ", "))
+		}
+
+		path := input.fullPath(route.Path)
+		// Param names are dropped so paths can be compared regardless of them.
+		key := method + " " + pathParamPattern.ReplaceAllString(path, "{$2}")
+		first, ok := seen[key]
+		if !ok {
+			first = &seenRoute{index: i + 1, path: path}
+			seen[key] = first
+		} else if first.path != path {
+			return fmt.Errorf("route #%d (%s %s): conflicts with route #%d (%s %s), the same path with different param names",
+				i+1, method, path, first.index, method, first.path)
+		}
+		if route.Match != nil {
+			continue
+		}
+		if first.fallback != 0 {
+			return fmt.Errorf("route #%d (%s %s): duplicate of route #%d; routes sharing method+path need a \"match\" block on all but one of them",
+				i+1, method, path, first.fallback)
+		}
+		first.fallback = i + 1
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateMethod(t *testing.T) {
+	_, err := BuildRouter(parseInput(t, `{"routes": [
+		{"method": "GET", "path": "/ok"},
+		{"method": "GETT", "path": "/users"}
+	]}`))
+	if err == nil {
+		t.Fatal("BuildRouter accepted method GETT")
+	}
+	for _, want := range []string{"route #2", "/users", `"GETT"`, "GET, HEAD, POST"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	srv := newTestServer(t, `{"routes": [{"method": " post ", "path": "/users", "response": {"status": 201}}]}`)
+	if res, _ := post(t, srv.URL+"/users", `{}`); res.StatusCode != 201 {
+		t.Errorf("lowercase method: status = %d, want 201", res.StatusCode)
+	}
+}
+
+func TestValidateDuplicateRoutes(t *testing.T) {
+	tests := []struct {
+		name, routes, want string
+	}{
+		{"duplicate", `{"method": "GET", "path": "/users"}, {"method": "get", "path": "/users"}`,
+			`route #2 (GET /users): duplicate of route #1`},
+		{"param names", `{"method": "GET", "path": "/users/{id}"}, {"method": "GET", "path": "/users/{userId}"}`,
+			`route #2 (GET /users/{userId}): conflicts with route #1 (GET /users/{id})`},
+		{"param names with regexps", `{"method": "GET", "path": "/users/{id:[0-9]+}"}, {"method": "GET", "path": "/users/{uid:[0-9]+}"}`,
+			`route #2 (GET /users/{uid:[0-9]+}): conflicts with route #1 (GET /users/{id:[0-9]+})`},
+		{"two fallbacks among variants", `{"method": "GET", "path": "/a"}, {"method": "GET", "path": "/a", "match": {"headers": {"X": ""}}}, {"method": "GET", "path": "/a"}`,
+			`route #3 (GET /a): duplicate of route #1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildRouter(parseInput(t, `{"routes": [`+tt.routes+`]}`))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	_, err := BuildRouter(parseInput(t, `{"routes": [
+		{"method": "GET", "path": "/a", "match": {"headers": {"X": ""}}},
+		{"method": "GET", "path": "/a"},
+		{"method": "POST", "path": "/a"}
+	]}`))
+	if err != nil {
+		t.Errorf("match variants with one fallback: %v", err)
+	}
+}
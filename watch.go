@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// watchPollInterval is how often -watch checks the config files for changes.
+const watchPollInterval = 100 * time.Millisecond
+
+// swapHandler serves through a handler that can be replaced at runtime, so a
+// reloaded config takes effect without restarting the listener.
+type swapHandler struct {
+	current atomic.Pointer[http.Handler]
+}
+
+// newSwapHandler returns a swapHandler serving h.
+func newSwapHandler(h http.Handler) *swapHandler {
+	s := &swapHandler{}
+	s.swap(h)
+	return s
+}
+
+// swap replaces the handler used by new requests.
+func (s *swapHandler) swap(h http.Handler) {
+	s.current.Store(&h)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.current.Load()).ServeHTTP(w, r)
+}
+
+// debouncer runs fn once, delay after the last of a burst of triggers.
+//
+// Editors often write a file several times per save; debouncing turns those
+// events (and changes to several watched files at once) into one reload.
+type debouncer struct {
+	delay time.Duration
+	fn    func()
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+// newDebouncer returns a debouncer calling fn after delay of quiet.
+func newDebouncer(delay time.Duration, fn func()) *debouncer {
+	return &debouncer{delay: delay, fn: fn}
+}
+
+// trigger (re)starts the quiet period.
+func (d *debouncer) trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.delay, d.fn)
+}
+
+// stop cancels a pending call.
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// fileStamp identifies a version of a file by size and modification time.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// statFile returns the current stamp of path; a missing file has a zero stamp.
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}
+}
+
+// watchFiles polls the files listed by paths until ctx is cancelled and
+// calls reload once they have stopped changing for debounce. paths is asked
+// again on every poll, so files that a reload starts reading (e.g. a new
+// extends) are watched from then on.
+func watchFiles(ctx context.Context, paths func() []string, debounce time.Duration, reload func()) {
+	stamps := make(map[string]fileStamp)
+	for _, p := range paths() {
+		stamps[p] = statFile(p)
+	}
+
+	d := newDebouncer(debounce, reload)
+	defer d.stop()
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, p := range paths() {
+			stamp := statFile(p)
+			old, seen := stamps[p]
+			stamps[p] = stamp
+			if seen && stamp != old {
+				d.trigger()
+			}
+		}
+	}
+}
+
+// reloadRouter rebuilds the router with load and swaps it in. A broken
+// config is reported and the previous routes keep serving.
+//
+// It reports whether the reload succeeded.
+func reloadRouter(s *swapHandler, load func() (inputType, error)) bool {
+	input, err := load()
+	if err == nil {
+		var router http.Handler
+		if router, err = BuildRouter(input); err == nil {
+			s.swap(router)
+			fmt.Println("🔁 Config reloaded")
+			return true
+		}
+	}
+	fmt.Printf("❌ Config reload failed, keeping the previous routes: %v\n", err)
+	return false
+}
+
+// runWatchCmd runs the -watch-cmd command line through the shell with the
+// config path appended as an argument, printing its combined output.
+func runWatchCmd(ctx context.Context, command, configPath string) {
+	name, args := "sh", []string{"-c", command + ` "$0"`, configPath}
+	if runtime.GOOS == "windows" {
+		name, args = "cmd", []string{"/C", command, configPath}
+	}
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if len(out) > 0 {
+		fmt.Printf("🔧 %s:\n%s", command, out)
+		if out[len(out)-1] != '\n' {
+			fmt.Println()
+		}
+	}
+	if err != nil {
+		fmt.Printf("❌ -watch-cmd failed: %v\n", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebouncer(t *testing.T) {
+	var calls atomic.Int32
+	d := newDebouncer(50*time.Millisecond, func() { calls.Add(1) })
+	for range 5 {
+		d.trigger()
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("5 rapid triggers ran fn %d times, want 1", n)
+	}
+}
+
+func TestWatchFilesDebounce(t *testing.T) {
+	dir := t.TempDir()
+	config := writeConfig(t, dir, "mocker.json", `{}`)
+	override := writeConfig(t, dir, "local.json", `{}`)
+
+	var reloads atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchFiles(ctx, func() []string { return []string{config, override} }, 300*time.Millisecond, func() { reloads.Add(1) })
+	time.Sleep(50 * time.Millisecond) // let the watcher take its first stamps
+
+	// An editor saving twice, then a change to the second file shortly after.
+	for i, path := range []string{config, config, override, config} {
+		if err := os.WriteFile(path, []byte(`{"port": "`+strings.Repeat("9", i+1)+`"}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(60 * time.Millisecond)
+	}
+	time.Sleep(700 * time.Millisecond)
+	if n := reloads.Load(); n != 1 {
+		t.Errorf("burst of edits caused %d reloads, want 1", n)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "mocker.json"), []byte(`{"port": "1"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(700 * time.Millisecond)
+	if n := reloads.Load(); n != 2 {
+		t.Errorf("a later edit brought the reload count to %d, want 2", n)
+	}
+}
+
+func TestWatchFilesExtendsChain(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "base.json", `{"routes": []}`)
+	config := writeConfig(t, dir, "mocker.json", `{}`)
+
+	// The watched files come from the last load, which later picks up an
+	// extends of base.json.
+	var files atomic.Pointer[[]string]
+	files.Store(&[]string{config})
+	var reloads atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchFiles(ctx, func() []string { return *files.Load() }, 50*time.Millisecond, func() { reloads.Add(1) })
+	time.Sleep(50 * time.Millisecond)
+
+	files.Store(&[]string{config, base})
+	time.Sleep(300 * time.Millisecond)
+	if n := reloads.Load(); n != 0 {
+		t.Fatalf("starting to watch base.json caused %d reloads, want 0", n)
+	}
+	if err := os.WriteFile(base, []byte(`{"port": "9"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if n := reloads.Load(); n != 1 {
+		t.Errorf("editing the extended base.json caused %d reloads, want 1", n)
+	}
+}
+
+func TestReloadRunsWatchCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command uses sh syntax")
+	}
+	dir := t.TempDir()
+	configPath := writeConfig(t, dir, "mocker.json", `{"routes": [{"method": "GET", "path": "/v", "response": {"status": 200, "body": 2}}]}`)
+	marker := filepath.Join(dir, "marker")
+
+	handler := newSwapHandler(http.NotFoundHandler())
+	load := func() (inputType, error) {
+		input, _, err := loadConfig(configPath, "")
+		input.LogOutput = io.Discard
+		return input, err
+	}
+	if !reloadRouter(handler, load) {
+		t.Fatal("reload failed")
+	}
+	runWatchCmd(context.Background(), "printf '%s' >"+marker, configPath)
+
+	got, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("the command did not run: %v", err)
+	}
+	if string(got) != configPath {
+		t.Errorf("command argument = %q, want the config path %q", got, configPath)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v", nil))
+	if rec.Body.String() != "2" {
+		t.Errorf("after reload: body = %q, want 2", rec.Body)
+	}
+}
+
+func TestReloadRouterKeepsRoutesOnError(t *testing.T) {
+	old := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	handler := newSwapHandler(old)
+	if reloadRouter(handler, func() (inputType, error) { return inputType{}, errors.New("bad JSON") }) {
+		t.Error("reloadRouter reported success for a broken config")
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want the previous handler's 418", rec.Code)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// whenExpr is a compiled match "when" expression, a boolean condition over
+// the request such as:
+//
+//	query.q == "foo" && header.Authorization != ""
+//
+// Operands are string, number and boolean literals or request fields:
+//
+//   - method, path
+//   - query.NAME, header.NAME, param.NAME (path param)
+//   - body.a.b[0] (JSONPath into the request body decoded as JSON)
+//
+// Missing fields are "". Operators are ==, !=, <, <=, >, >= (numeric when
+// both sides are numbers, string comparison otherwise), !, && and ||, with
+// parentheses for grouping. An operand on its own is true unless it is "",
+// false, 0 or missing, so `header.Authorization` checks presence.
+type whenExpr struct {
+	root     whenNode
+	needBody bool // the expression reads body.*, so the body must be decoded
+}
+
+// whenNode is a node of the expression tree.
+type whenNode interface {
+	eval(ctx *whenContext) any
+}
+
+// whenContext is the request an expression is evaluated against.
+type whenContext struct {
+	r    *http.Request
+	body any // request body decoded as JSON; nil when not JSON or not needed
+}
+
+// compileWhen parses a when expression.
+func compileWhen(src string) (*whenExpr, error) {
+	p := &whenParser{src: src}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("invalid when %q: %w", src, err)
+	}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid when %q: %w", src, err)
+	}
+	return &whenExpr{root: root, needBody: p.needBody}, nil
+}
+
+// matches evaluates the expression for the request.
+func (e *whenExpr) matches(r *http.Request) bool {
+	ctx := &whenContext{r: r}
+	if e.needBody {
+		if raw, err := readRequestBody(r); err == nil && len(raw) > 0 {
+			_ = json.Unmarshal(raw, &ctx.body) // non-JSON bodies are left nil
+		}
+	}
+	return truthy(e.root.eval(ctx))
+}
+
+// whenToken is a lexical token: an operator, a literal or a field reference.
+type whenToken struct {
+	kind string // "op", "string", "number", "ident"
+	text string
+}
+
+// whenParser is a recursive-descent parser over the token list.
+type whenParser struct {
+	src      string
+	tokens   []whenToken
+	pos      int
+	needBody bool
+}
+
+// whenOperators are matched longest first.
+var whenOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+// tokenize splits src into tokens.
+func (p *whenParser) tokenize() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(s) && s[end] != s[i] {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return fmt.Errorf("unterminated string")
+			}
+			text := s[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(s[i : end+1])
+				if err != nil {
+					return fmt.Errorf("invalid string %s", s[i:end+1])
+				}
+				text = unquoted
+			}
+			p.tokens = append(p.tokens, whenToken{kind: "string", text: text})
+			i = end + 1
+		case c == '-' || unicode.IsDigit(c):
+			end := i + 1
+			for end < len(s) && (unicode.IsDigit(rune(s[end])) || s[end] == '.') {
+				end++
+			}
+			p.tokens = append(p.tokens, whenToken{kind: "number", text: s[i:end]})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i
+			for end < len(s) && !unicode.IsSpace(rune(s[end])) && !strings.ContainsRune("=!<>&|()\"'", rune(s[end])) {
+				end++
+			}
+			p.tokens = append(p.tokens, whenToken{kind: "ident", text: s[i:end]})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range whenOperators {
+				if strings.HasPrefix(s[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("unexpected %q", s[i:i+1])
+			}
+			p.tokens = append(p.tokens, whenToken{kind: "op", text: op})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+// accept consumes the next token when it is the operator op.
+func (p *whenParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "op" && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *whenParser) parseOr() (whenNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var right whenNode
+		if right, err = p.parseAnd(); err == nil {
+			left = whenLogical{op: "||", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *whenParser) parseAnd() (whenNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.accept("&&") {
+		var right whenNode
+		if right, err = p.parseUnary(); err == nil {
+			left = whenLogical{op: "&&", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *whenParser) parseUnary() (whenNode, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		return whenNot{operand}, err
+	}
+	return p.parseComparison()
+}
+
+func (p *whenParser) parseComparison() (whenNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.parseOperand()
+			return whenCompare{op: op, left: left, right: right}, err
+		}
+	}
+	return left, nil
+}
+
+func (p *whenParser) parseOperand() (whenNode, error) {
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err == nil && !p.accept(")") {
+			err = fmt.Errorf("missing )")
+		}
+		return inner, err
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case "string":
+		return whenLiteral{tok.text}, nil
+	case "number":
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return whenLiteral{n}, nil
+	case "ident":
+		return p.field(tok.text)
+	}
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+// field resolves a request field reference.
+func (p *whenParser) field(name string) (whenNode, error) {
+	switch name {
+	case "true":
+		return whenLiteral{true}, nil
+	case "false":
+		return whenLiteral{false}, nil
+	case "method", "path":
+		return whenField{source: name}, nil
+	}
+	source, key, ok := strings.Cut(name, ".")
+	if !ok || key == "" {
+		return nil, fmt.Errorf("unknown field %q (use method, path, query.*, header.*, param.* or body.*)", name)
+	}
+	switch source {
+	case "query", "header", "param":
+		return whenField{source: source, key: key}, nil
+	case "body":
+		if _, err := parseJSONPath(key); err != nil {
+			return nil, err
+		}
+		p.needBody = true
+		return whenField{source: source, key: key}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q (use method, path, query.*, header.*, param.* or body.*)", name)
+}
+
+type whenLiteral struct{ value any }
+
+func (n whenLiteral) eval(*whenContext) any { return n.value }
+
+type whenField struct{ source, key string }
+
+func (n whenField) eval(ctx *whenContext) any {
+	r := ctx.r
+	switch n.source {
+	case "method":
+		return r.Method
+	case "path":
+		return r.URL.Path
+	case "query":
+		return r.URL.Query().Get(n.key)
+	case "header":
+		return r.Header.Get(n.key)
+	case "param":
+		return chi.URLParam(r, n.key)
+	}
+	v, _ := evalJSONPath(ctx.body, n.key)
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+type whenNot struct{ operand whenNode }
+
+func (n whenNot) eval(ctx *whenContext) any { return !truthy(n.operand.eval(ctx)) }
+
+type whenLogical struct {
+	op          string
+	left, right whenNode
+}
+
+func (n whenLogical) eval(ctx *whenContext) any {
+	if n.op == "&&" {
+		return truthy(n.left.eval(ctx)) && truthy(n.right.eval(ctx))
+	}
+	return truthy(n.left.eval(ctx)) || truthy(n.right.eval(ctx))
+}
+
+type whenCompare struct {
+	op          string
+	left, right whenNode
+}
+
+func (n whenCompare) eval(ctx *whenContext) any {
+	left, right := n.left.eval(ctx), n.right.eval(ctx)
+	var cmp int
+	if a, ok := toNumber(left); ok {
+		if b, ok := toNumber(right); ok {
+			switch {
+			case a < b:
+				cmp = -1
+			case a > b:
+				cmp = 1
+			}
+			return compareResult(n.op, cmp)
+		}
+	}
+	cmp = strings.Compare(toString(left), toString(right))
+	return compareResult(n.op, cmp)
+}
+
+// compareResult applies a comparison operator to a three-way comparison.
+func compareResult(op string, cmp int) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// toNumber converts numbers and numeric strings (e.g. query params).
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toString renders an operand for string comparison; JSON values other than
+// strings compare by their JSON encoding.
+func toString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	out, _ := json.Marshal(v)
+	return string(out)
+}
+
+// truthy reports whether an operand counts as true on its own.
+func truthy(v any) bool {
+	switch b := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return b
+	case string:
+		return b != ""
+	case float64:
+		return b != 0
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWhenExpr(t *testing.T) {
+	newRequest := func(target, auth, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		return r
+	}
+
+	for _, tt := range []struct {
+		expr string
+		r    *http.Request
+		want bool
+	}{
+		{`query.q == "foo"`, newRequest("/s?q=foo", "", ""), true},
+		{`query.q == "foo"`, newRequest("/s?q=bar", "", ""), false},
+		{`query.q != "foo"`, newRequest("/s", "", ""), true},
+		{`header.Authorization`, newRequest("/s", "Bearer x", ""), true},
+		{`header.Authorization`, newRequest("/s", "", ""), false},
+		{`!header.Authorization`, newRequest("/s", "", ""), true},
+		{`query.q == "foo" && header.Authorization != ""`, newRequest("/s?q=foo", "Bearer x", ""), true},
+		{`query.q == "foo" && header.Authorization != ""`, newRequest("/s?q=foo", "", ""), false},
+		{`query.q == "foo" || header.Authorization != ""`, newRequest("/s", "Bearer x", ""), true},
+		{`!(query.a == "1" || query.b == "1") && method == "POST"`, newRequest("/s?c=1", "", ""), true},
+		{`query.page >= 10`, newRequest("/s?page=9", "", ""), false},
+		{`query.page >= 10`, newRequest("/s?page=10", "", ""), true},
+		{`query.page > 9`, newRequest("/s?page=10", "", ""), true}, // numeric, not "10" < "9"
+		{`body.user.role == "admin" && body.items[1] == 2`, newRequest("/s", "", `{"user": {"role": "admin"}, "items": [1, 2]}`), true},
+		{`body.user.role == "admin"`, newRequest("/s", "", `not json`), false},
+		{`body.active`, newRequest("/s", "", `{"active": false}`), false},
+		{`path == "/s" && true`, newRequest("/s", "", ""), true},
+	} {
+		e, err := compileWhen(tt.expr)
+		if err != nil {
+			t.Fatalf("compileWhen(%s): %v", tt.expr, err)
+		}
+		if got := e.matches(tt.r); got != tt.want {
+			t.Errorf("%s on %s: %v, want %v", tt.expr, tt.r.URL, got, tt.want)
+		}
+	}
+}
+
+func TestCompileWhenErrors(t *testing.T) {
+	for _, expr := range []string{
+		`query.q ==`,
+		`query.q == "foo`,
+		`(query.q == "foo"`,
+		`cookie.session`,
+		`query.`,
+		`query.q = "foo"`,
+		`body.items[x]`,
+	} {
+		if _, err := compileWhen(expr); err == nil {
+			t.Errorf("compileWhen(%s) accepted", expr)
+		}
+	}
+}
+
+func TestMatchWhen(t *testing.T) {
+	srv := newTestServer(t, `{"routes": [
+		{"method": "GET", "path": "/search/{kind}", "match": {"when": "param.kind == \"users\" && query.q != \"\""}, "response": {"status": 200, "body": "users"}},
+		{"method": "GET", "path": "/search/{kind}", "response": {"status": 200, "body": "fallback"}}
+	]}`)
+	for path, want := range map[string]string{
+		"/search/users?q=ada": `"users"`,
+		"/search/users":       `"fallback"`,
+		"/search/teams?q=ada": `"fallback"`,
+	} {
+		if _, body := get(t, srv.URL+path); body != want {
+			t.Errorf("%s: body = %s, want %s", path, body, want)
+		}
+	}
+}